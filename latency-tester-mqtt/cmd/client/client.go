@@ -20,6 +20,7 @@ func main() {
 	interval := flag.Uint("interval", 100, "send interval time (ms)")
 	requestSize := flag.Uint("requestSize", 1024, "bytes of the payload")
 	log := flag.String("log", "./log.csv", "file to store latency results")
+	format := flag.String("format", "csv", "format of the latency results (csv, jsonl or protobuf)")
 	qos := flag.Uint("qos", 0, "mqtt QoS")
 	klog.InitFlags(nil)
 	flag.Parse()
@@ -29,6 +30,7 @@ func main() {
 	klog.Infof("Interval: %v ms", *interval)
 	klog.Infof("Request Size: %v Bytes", *requestSize)
 	klog.Infof("QoS: %v", byte(*qos))
+	klog.Infof("Output format: %v", *format)
 
 	logic.ConfigureLogging()
 
@@ -42,7 +44,7 @@ func main() {
 	signal.Notify(shutdown, os.Interrupt)
 	signal.Notify(shutdown, syscall.SIGTERM)
 
-	subscriber := logic.NewClientSubscriber(client, *log, *repetitions, byte(*qos), shutdown)
+	subscriber := logic.NewClientSubscriber(client, *log, *format, *repetitions, byte(*qos), shutdown)
 	subscriber.Subscribe()
 
 	requester := logic.NewClientRequester(client, *repetitions, *interval, *requestSize, byte(*qos), shutdown)
@@ -51,4 +53,5 @@ func main() {
 	<-shutdown
 	klog.Info("Exiting")
 	client.Disconnect(logic.DisconnectQuiescence)
+	subscriber.Cleanup()
 }
@@ -5,6 +5,13 @@ go 1.16
 require (
 	github.com/eclipse/paho.mqtt.golang v1.3.4
 	github.com/golang/protobuf v1.5.2
+	github.com/richiMarchi/latency-tester/enhanced-client/client v0.0.0
 	google.golang.org/protobuf v1.26.0
 	k8s.io/klog/v2 v2.8.0
 )
+
+// The result sink is shared with the WebSocket client
+replace github.com/richiMarchi/latency-tester/enhanced-client/client => ../latency-tester-websocket/enhanced-client/client
+
+// The client requires the server module for the protocol it speaks
+replace github.com/richiMarchi/latency-tester/server => ../latency-tester-websocket/server
@@ -2,10 +2,10 @@ package logic
 
 import (
 	"crypto/rand"
-	"fmt"
 	"os"
 	"time"
 
+	"github.com/richiMarchi/latency-tester/enhanced-client/client/sink"
 	serialization "github.com/richiMarchi/latency-tester/latency-tester-mqtt/pkg/message/serialization/protobuf"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
@@ -27,7 +27,7 @@ type ClientRequester struct {
 
 type ClientSubscriber struct {
 	client      mqtt.Client
-	output      *os.File
+	output      sink.ResultSink
 	repetitions uint
 	qos         byte
 	received    uint
@@ -52,16 +52,15 @@ func NewClientRequester(client mqtt.Client, repetitions, interval, requestSize u
 	}
 }
 
-func NewClientSubscriber(client mqtt.Client, outputFile string, repetitions uint, qos byte, shutdown chan os.Signal) *ClientSubscriber {
-	file, err := os.Create(outputFile)
+func NewClientSubscriber(client mqtt.Client, outputFile, format string, repetitions uint, qos byte, shutdown chan os.Signal) *ClientSubscriber {
+	output, err := sink.Create(format, outputFile, nil)
 	if err != nil {
 		klog.Fatal("Failed to open output file: ", err)
 	}
-	_, _ = file.WriteString("client-send-timestamp,server-timestamp,e2e-rtt\n")
 
 	return &ClientSubscriber{
 		client:      client,
-		output:      file,
+		output:      output,
 		repetitions: repetitions,
 		received:    0,
 		qos:         qos,
@@ -127,7 +126,9 @@ func (s *ClientSubscriber) Subscribe() {
 }
 
 func (s *ClientSubscriber) Cleanup() {
-	s.output.Close()
+	if err := s.output.Close(); err != nil {
+		klog.Errorf("Failed to flush output file: %v", err)
+	}
 }
 
 func (s *ClientSubscriber) onMessage(client mqtt.Client, msg mqtt.Message) {
@@ -142,11 +143,14 @@ func (s *ClientSubscriber) onMessage(client mqtt.Client, msg mqtt.Message) {
 
 	klog.Infof("Received message %d in %.2f ms", response.Id, latencyMs)
 
-	outputStr := fmt.Sprintf("%v,%v,%f\n",
-		response.ClientTimestamp.AsTime().UnixNano(),
-		response.ServerTimestamp.AsTime().UnixNano(),
-		latencyMs)
-	_, _ = s.output.WriteString(outputStr)
+	err = s.output.Write(sink.Record{
+		ClientTimestamp: response.ClientTimestamp.AsTime(),
+		ServerTimestamp: response.ServerTimestamp.AsTime(),
+		Rtt:             latencyMs,
+	})
+	if err != nil {
+		klog.Errorf("Failed to write result: %v", err)
+	}
 
 	s.received += 1
 	if s.received == s.repetitions {
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"github.com/richiMarchi/latency-tester/server/echo"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// Prefix of the files storing the baseline rtt measured towards the loopback server
+const CalibrationPrefix = "calibration"
+
+// Run the calibration command, storing the baseline in the given campaign folder
+func calibrateCommand(settingsFile string, campaignDir string) {
+	const LoggerHdr = "@calibrate     - "
+
+	file, err := ioutil.ReadFile(settingsFile)
+	if err != nil {
+		log.Fatal(LoggerHdr+"*** ERROR reading settings file:", err)
+	}
+	var settings Settings
+	err = yaml.Unmarshal(file, &settings)
+	if err != nil {
+		log.Fatal(LoggerHdr+"*** ERROR reading settings:", err)
+	}
+	if campaignDir[len(campaignDir)-1] != '/' {
+		campaignDir += "/"
+	}
+	err = os.MkdirAll(campaignDir+DataDirName, os.ModePerm)
+	if err != nil {
+		log.Fatal(LoggerHdr+"*** ERROR creating the data folder:", err)
+	}
+	calibrate(settings, campaignDir+DataDirName)
+}
+
+// Run the client towards an in-process server on the loopback interface for every interval and message size, in order
+// to measure the rtt added by the tool itself
+func calibrate(settings Settings, dataDir string) {
+	const LoggerHdr = "@calibrate     - "
+
+	if settings.CalibrationStep == 0 {
+		settings.CalibrationStep = 10
+	}
+	// The server is the real one, logging its sessions in the data folder instead of the output of the campaign
+	serverLog, err := os.Create(dataDir + CalibrationPrefix + "-server.log")
+	if err != nil {
+		log.Println(LoggerHdr+"*** ERROR creating the loopback server log:", err)
+		return
+	}
+	defer serverLog.Close()
+	loopback, err := echo.New(echo.Config{Logger: log.New(serverLog, "", log.LstdFlags)})
+	if err != nil {
+		log.Println(LoggerHdr+"*** ERROR creating the loopback server:", err)
+		return
+	}
+	defer loopback.Close()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Println(LoggerHdr+"*** ERROR starting the loopback server:", err)
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/echo", loopback)
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	defer server.Close()
+	log.Println(LoggerHdr + "Loopback server listening on " + listener.Addr().String())
+
+	for _, inter := range settings.Intervals {
+		for _, size := range settings.MsgSizes {
+			log.Println(LoggerHdr + "Calibration - Inter: " + inter.String() + " - Msg: " + strconv.Itoa(size))
+			// The low-noise mode of the runs is applied to the calibration too, to measure the same overhead
+			clientArgs := append([]string{
+				"-reps=" + strconv.Itoa(inter.Repetitions(settings.CalibrationStep)),
+				inter.ClientArg(),
+				"-requestPayload=" + strconv.Itoa(size),
+				"-responsePayload=" + strconv.Itoa(settings.ResponseSize),
+				"-warmup=" + settings.Warmup,
+				"-cooldown=" + settings.Cooldown,
+				"-log=" + dataDir + CalibrationPrefix + ".i" + inter.Label() + ".x" + strconv.Itoa(size),
+			}, lowNoiseArgs(settings)...)
+			runClient(append(clientArgs, listener.Addr().String()), settings.InProcessClient)
+		}
+	}
+}
@@ -1,100 +1,50 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
-	"github.com/gorilla/websocket"
+	"github.com/richiMarchi/latency-tester/enhanced-client/client/prober"
 	"log"
 	"os"
 	"os/signal"
-	"sync"
 )
 
-var reps = flag.Uint64("reps", 0, "number of repetitions")
-var logFile = flag.String("log", "/execdir/log", "file to store latency numbers")
-var requestBytes = flag.Uint64("requestPayload", 64, "bytes of the payload")
-var responseBytes = flag.Uint64("responsePayload", 64, "bytes of the response payload")
-var interval = flag.Uint64("interval", 1000, "send interval time (ms)")
-var https = flag.Bool("tls", false, "true if TLS enabled")
-var tracerouteIp = flag.String("traceroute", "", "traceroute ip if requested")
-var sockOpt = flag.Bool("tcpStats", false, "true if TCP Stats requested")
-var srcPort = flag.Int("srcPort", 0, "client source port")
-var address string
-
 func main() {
-	flag.Parse()
-	address = flag.Arg(0)
 	log.SetFlags(0)
-
-	if address == "" {
-		log.Fatal("Server address required")
+	config, err := prober.ConfigFromArgs(os.Args[1:])
+	if err != nil {
+		if err != flag.ErrHelp {
+			log.Println(err)
+		}
+		os.Exit(2)
 	}
+	// The JSON lines are the only output on stdout, in order to pipe them into other tools
+	config.Verbose = !config.JsonLines
 
-	printLogs()
+	p, err := prober.New(config)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	// Handle SIGINT as channel
+	// Handle SIGINT cancelling the context, in order to stop all the senders
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		cancel()
+	}()
 
-	// Create websocket communication channel
-	conn := connect()
-	defer conn.Close()
-
-	// File creation
-	toolRtt, toolFileErr := os.Create(*logFile + ".csv")
-	if toolFileErr != nil {
-		log.Fatalf("failed creating file: %s", toolFileErr)
+	if err = p.Connect(); err != nil {
+		log.Fatal(err)
 	}
-	toolRtt.WriteString("#client-send-timestamp,server-timestamp,e2e-rtt\n")
-	defer toolRtt.Close()
-
-	if *tracerouteIp != "" {
-		tracerouteFile, tracerouteFileErr := os.Create(*logFile + "_traceroute")
-		if tracerouteFileErr != nil {
-			log.Fatalf("failed creating file: %s", tracerouteFileErr)
-		}
-
-		log.Println("Starting traceroute to", *tracerouteIp+"...")
-		customTraceroute(*tracerouteIp, tracerouteFile)
-		tracerouteFile.Close()
-		log.Println("Traceroute completed!")
-		log.Println()
+	if err = p.Run(ctx); err != nil {
+		log.Fatal(err)
 	}
-
-	// Create synchronization channels
-	doneRead := make(chan struct{})
-	reset := make(chan *websocket.Conn, 2)
-
-	// Parallel read dispatcher
-	go readDispatcher(conn, doneRead, toolRtt, reset)
-
-	var wg sync.WaitGroup
-	ssReading := true
-	var msgId int32 = 0
-
-	// If explicitly requested tcp stats handlers
-	if *sockOpt {
-		tcpStats, tcpStatsFileErr := os.Create(*logFile + "_tcp-stats.csv")
-		if tcpStatsFileErr != nil {
-			log.Fatalf("failed creating file: %s", tcpStatsFileErr)
-		}
-		tcpStats.WriteString("#timestamp,message-id,state,ca_state,retransmits,probes,backoff,options,pad_cgo_0-0," +
-			"pad_cgo_0-1,rto,ato,snd_mss,rcv_mss,unacked,sacked,lost,retrans,fackets,last_data_sent,last_ack_sent," +
-			"last_data_recv,last_ack_recv,pmtu,rcv_ssthresh,rtt,rttvar,snd_ssthresh,snd_cwnd,advmss,reordering,rcv_rtt," +
-			"rcv_space,total_retrans\n")
-		wg.Add(1)
-		go getSocketStats(conn, &ssReading, tcpStats, &wg, &msgId, reset)
+	if config.Verbose {
+		fmt.Println()
+		fmt.Println("Everything is completed!")
 	}
-
-	// Start making requests
-	requestSender(conn, interrupt, &ssReading, reset, &msgId)
-
-	// Stop all go routines
-	ssReading = false
-
-	// Wait for the go routines to complete their job
-	<-doneRead
-	wg.Wait()
-	fmt.Println()
-	fmt.Println("Everything is completed!")
 }
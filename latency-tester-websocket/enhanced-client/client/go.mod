@@ -7,5 +7,11 @@ require (
 	github.com/golang/protobuf v1.5.1
 	github.com/google/go-cmp v0.5.5
 	github.com/gorilla/websocket v1.4.2
+	github.com/richiMarchi/latency-tester/server v0.0.0
+	golang.org/x/sys v0.0.0-20210615035016-665e8c7367d1
 	google.golang.org/protobuf v1.26.0
+	gopkg.in/yaml.v2 v2.4.0
 )
+
+// The protocol spoken with the server is the one of the server module
+replace github.com/richiMarchi/latency-tester/server => ../../server
@@ -0,0 +1,179 @@
+// Package hoststats samples the load of the host (CPU utilisation, load average, softirq time and network interface
+// counters) into a csv file, so that the latency spikes caused by the host itself can be told apart from the network
+// ones. It reads the Linux /proc files.
+package hoststats
+
+import (
+	"bufio"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Header of the host statistics file. The CPU and softirq utilisations are percentages of the whole CPU time since the
+// previous sample, the network counters are the ones since the previous sample, summed over all the interfaces except
+// the loopback one
+const Header = "#timestamp,cpu,softirq,load-1,load-5,load-15,rx-bytes,tx-bytes,rx-packets,tx-packets,rx-drops,tx-drops"
+
+// Counters of the host at a given time
+type counters struct {
+	cpuTotal   uint64
+	cpuBusy    uint64
+	cpuSoftirq uint64
+	// rx-bytes, tx-bytes, rx-packets, tx-packets, rx-drops and tx-drops
+	net [6]uint64
+}
+
+// Samples the host statistics into a file until stopped
+type Recorder struct {
+	file     *os.File
+	period   time.Duration
+	previous counters
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// Create the file and record a sample every period, until the recorder is stopped
+func Start(filename string, period time.Duration) (*Recorder, error) {
+	if period <= 0 {
+		return nil, errors.New("host stats: the sampling period has to be positive")
+	}
+	first, err := readCounters()
+	if err != nil {
+		return nil, errors.New("host stats: " + err.Error())
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	file.WriteString(Header + "\n")
+	r := &Recorder{
+		file:     file,
+		period:   period,
+		previous: first,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go r.record()
+	return r, nil
+}
+
+// Stop sampling and close the file
+func (r *Recorder) Stop() {
+	close(r.stop)
+	<-r.done
+	r.file.Close()
+}
+
+func (r *Recorder) record() {
+	defer close(r.done)
+	ticker := time.NewTicker(r.period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.sample()
+		}
+	}
+}
+
+// Write the utilisation and the counters since the previous sample, skipping the sample if the files cannot be read
+func (r *Recorder) sample() {
+	timestamp := time.Now()
+	current, err := readCounters()
+	if err != nil {
+		return
+	}
+	load, err := readLoadAverage()
+	if err != nil {
+		return
+	}
+	cpu, softirq := 0.0, 0.0
+	if total := current.cpuTotal - r.previous.cpuTotal; total != 0 {
+		cpu = float64(current.cpuBusy-r.previous.cpuBusy) / float64(total) * 100
+		softirq = float64(current.cpuSoftirq-r.previous.cpuSoftirq) / float64(total) * 100
+	}
+	line := strconv.FormatInt(timestamp.UnixNano(), 10) + "," +
+		strconv.FormatFloat(cpu, 'f', 2, 64) + "," + strconv.FormatFloat(softirq, 'f', 2, 64) + "," +
+		strings.Join(load, ",")
+	for i := range current.net {
+		// The counters of an interface removed in the meantime are no longer summed
+		delta := uint64(0)
+		if current.net[i] > r.previous.net[i] {
+			delta = current.net[i] - r.previous.net[i]
+		}
+		line += "," + strconv.FormatUint(delta, 10)
+	}
+	r.file.WriteString(line + "\n")
+	r.previous = current
+}
+
+// Read the CPU times from /proc/stat and the interface counters from /proc/net/dev
+func readCounters() (counters, error) {
+	var c counters
+	stat, err := os.Open("/proc/stat")
+	if err != nil {
+		return c, err
+	}
+	defer stat.Close()
+	scanner := bufio.NewScanner(stat)
+	// The first line sums the times of all the CPUs
+	if !scanner.Scan() {
+		return c, errors.New("empty /proc/stat")
+	}
+	// cpu user nice system idle iowait irq softirq steal guest guest_nice
+	fields := strings.Fields(scanner.Text())
+	if len(fields) < 9 || fields[0] != "cpu" {
+		return c, errors.New("unexpected /proc/stat format")
+	}
+	var times [8]uint64
+	for i := range times {
+		if times[i], err = strconv.ParseUint(fields[i+1], 10, 64); err != nil {
+			return c, err
+		}
+		c.cpuTotal += times[i]
+	}
+	c.cpuBusy = c.cpuTotal - times[3] - times[4]
+	c.cpuSoftirq = times[6]
+
+	netDev, err := os.Open("/proc/net/dev")
+	if err != nil {
+		return c, err
+	}
+	defer netDev.Close()
+	scanner = bufio.NewScanner(netDev)
+	for line := 0; scanner.Scan(); line++ {
+		// Two header lines, then "iface: rx-bytes rx-packets rx-errs rx-drop ... tx-bytes tx-packets tx-errs tx-drop ..."
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if line < 2 || len(parts) != 2 || strings.TrimSpace(parts[0]) == "lo" {
+			continue
+		}
+		fields := strings.Fields(parts[1])
+		if len(fields) < 12 {
+			continue
+		}
+		for i, field := range []int{0, 8, 1, 9, 3, 11} {
+			value, _ := strconv.ParseUint(fields[field], 10, 64)
+			c.net[i] += value
+		}
+	}
+	return c, nil
+}
+
+// Read the 1, 5 and 15 minutes load averages from /proc/loadavg
+func readLoadAverage() ([]string, error) {
+	loadavg, err := ioutil.ReadFile("/proc/loadavg")
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(string(loadavg))
+	if len(fields) < 3 {
+		return nil, errors.New("unexpected /proc/loadavg format")
+	}
+	return fields[:3], nil
+}
@@ -0,0 +1,191 @@
+package prober
+
+import (
+	"encoding/csv"
+	"errors"
+	"github.com/richiMarchi/latency-tester/server/serialization/protobuf"
+	"hash/crc32"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Faults detected by the client
+const (
+	MissingFault   = "missing"
+	DuplicateFault = "duplicate"
+	ReorderedFault = "reordered"
+	CorruptedFault = "corrupted"
+	ResetFault     = "connection-reset"
+	InvalidFault   = "invalid"
+)
+
+// Faults injected by the server and the detected ones they cause, the delays being detectable only as rtt outliers
+var injectedFaults = []struct {
+	injected string
+	detected string
+}{
+	{"drop", MissingFault},
+	{"delay", ""},
+	{"duplicate", DuplicateFault},
+	{"reorder", ReorderedFault},
+	{"corrupt", CorruptedFault},
+	{"close", ResetFault},
+}
+
+// Messages sent and received on a stream. Only the gaps below the highest received message are stored, so that the
+// memory does not grow with the messages
+type streamFaults struct {
+	highestSent     int32
+	highestReceived int32
+	// Messages sent before the highest received one and not received yet
+	gaps map[int32]bool
+	// Messages replaced by the reset message of a new connection, hence never sent
+	unsent map[int32]bool
+}
+
+// Faults detected on the messages of a session
+type faultDetector struct {
+	sync.Mutex
+	streams map[int32]*streamFaults
+}
+
+func newFaultDetector() *faultDetector {
+	return &faultDetector{streams: make(map[int32]*streamFaults)}
+}
+
+func (d *faultDetector) stream(id int32) *streamFaults {
+	stream, present := d.streams[id]
+	if !present {
+		stream = &streamFaults{gaps: make(map[int32]bool), unsent: make(map[int32]bool)}
+		d.streams[id] = stream
+	}
+	return stream
+}
+
+// Track the message sent on the stream, the reset messages being sent in place of the message id
+func (d *faultDetector) sent(streamId, id int32, reset bool) {
+	d.Lock()
+	defer d.Unlock()
+	stream := d.stream(streamId)
+	if reset {
+		stream.unsent[id] = true
+	}
+	if id > stream.highestSent {
+		stream.highestSent = id
+	}
+}
+
+// Track the response received on the stream, returning the fault detected on it if any
+func (d *faultDetector) received(streamId, id int32) string {
+	d.Lock()
+	defer d.Unlock()
+	stream := d.stream(streamId)
+	if id > stream.highestReceived {
+		for missing := stream.highestReceived + 1; missing < id; missing++ {
+			if !stream.unsent[missing] {
+				stream.gaps[missing] = true
+			}
+		}
+		stream.highestReceived = id
+		return ""
+	}
+	if stream.gaps[id] {
+		delete(stream.gaps, id)
+		return ReorderedFault
+	}
+	return DuplicateFault
+}
+
+// Return the messages of each stream whose response has not been received
+func (d *faultDetector) missing() map[int32][]int32 {
+	d.Lock()
+	defer d.Unlock()
+	missing := make(map[int32][]int32)
+	for streamId, stream := range d.streams {
+		for id := range stream.gaps {
+			missing[streamId] = append(missing[streamId], id)
+		}
+		for id := stream.highestReceived + 1; id <= stream.highestSent; id++ {
+			if !stream.unsent[id] {
+				missing[streamId] = append(missing[streamId], id)
+			}
+		}
+	}
+	return missing
+}
+
+// True if the payload does not match the checksum set by the server, if any
+func corruptedPayload(jsonMap *protobuf.DataJSON) bool {
+	return jsonMap.PayloadChecksum != nil && crc32.ChecksumIEEE(jsonMap.Payload) != jsonMap.PayloadChecksum.Value
+}
+
+// Store the fault detected on the message of the session
+func (s *session) recordFault(streamId, id int32, fault string) {
+	s.client.faultCounts.Lock()
+	s.client.faultCounts.counts[fault]++
+	s.client.faultCounts.Unlock()
+	s.out.Lock()
+	defer s.out.Unlock()
+	faults := s.out.faults
+	faults.WriteString(strconv.FormatInt(getTimestamp().UnixNano(), 10))
+	faults.WriteString(",")
+	faults.WriteString(strconv.Itoa(int(streamId)))
+	faults.WriteString(",")
+	faults.WriteString(strconv.Itoa(int(id)))
+	faults.WriteString(",")
+	faults.WriteString(fault)
+	faults.WriteString(s.connectionColumn())
+	faults.WriteString("\n")
+}
+
+// Store the messages of the session whose response has not been received at the end of the run
+func (s *session) recordMissing() {
+	for streamId, ids := range s.faults.missing() {
+		for _, id := range ids {
+			s.recordFault(streamId, id, MissingFault)
+		}
+	}
+}
+
+// Compare the faults detected during the run with the ones injected by the server in the same time, read from the
+// faults log of the server
+func (c *client) crossCheckFaults(start, end time.Time) error {
+	f, err := os.Open(c.config.InjectedFaults)
+	if err != nil {
+		return errors.New("injectedFaults: " + err.Error())
+	}
+	defer f.Close()
+	reader := csv.NewReader(f)
+	reader.Comment = '#'
+	records, err := reader.ReadAll()
+	if err != nil {
+		return errors.New("injectedFaults: " + err.Error())
+	}
+	injected := make(map[string]int)
+	for _, record := range records {
+		if len(record) < 5 {
+			continue
+		}
+		ts, err := strconv.ParseInt(record[0], 10, 64)
+		if err != nil || ts < start.UnixNano() || ts > end.UnixNano() {
+			continue
+		}
+		injected[record[4]]++
+	}
+	c.faultCounts.Lock()
+	defer c.faultCounts.Unlock()
+	log.Println("Fault cross-check (injected by the server, detected by the client):")
+	for _, fault := range injectedFaults {
+		if fault.detected == "" {
+			log.Printf("%-10s injected %6d, not detectable", fault.injected, injected[fault.injected])
+			continue
+		}
+		log.Printf("%-10s injected %6d, %-16s detected %6d", fault.injected, injected[fault.injected],
+			fault.detected, c.faultCounts.counts[fault.detected])
+	}
+	log.Printf("%-10s %15s %-16s detected %6d", "", "", InvalidFault, c.faultCounts.counts[InvalidFault])
+	return nil
+}
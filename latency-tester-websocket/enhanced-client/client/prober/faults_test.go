@@ -0,0 +1,114 @@
+package prober
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// Message sent or response received on a stream, with the fault expected to be detected on the response
+type faultEvent struct {
+	sent   bool
+	stream int32
+	id     int32
+	reset  bool
+	fault  string
+}
+
+func sentMsg(stream, id int32) faultEvent {
+	return faultEvent{sent: true, stream: stream, id: id}
+}
+
+func sentReset(stream, id int32) faultEvent {
+	return faultEvent{sent: true, stream: stream, id: id, reset: true}
+}
+
+func receivedMsg(stream, id int32, fault string) faultEvent {
+	return faultEvent{stream: stream, id: id, fault: fault}
+}
+
+func TestFaultDetector(t *testing.T) {
+	tests := []struct {
+		name    string
+		events  []faultEvent
+		missing map[int32][]int32
+	}{
+		{
+			"in order",
+			[]faultEvent{sentMsg(1, 1), receivedMsg(1, 1, ""), sentMsg(1, 2), receivedMsg(1, 2, ""), sentMsg(1, 3),
+				receivedMsg(1, 3, "")},
+			map[int32][]int32{},
+		},
+		{
+			"lost in the middle",
+			[]faultEvent{sentMsg(1, 1), sentMsg(1, 2), sentMsg(1, 3), receivedMsg(1, 1, ""), receivedMsg(1, 3, "")},
+			map[int32][]int32{1: {2}},
+		},
+		{
+			"lost at the end",
+			[]faultEvent{sentMsg(1, 1), sentMsg(1, 2), sentMsg(1, 3), receivedMsg(1, 1, "")},
+			map[int32][]int32{1: {2, 3}},
+		},
+		{
+			"reordered",
+			[]faultEvent{sentMsg(1, 1), sentMsg(1, 2), sentMsg(1, 3), receivedMsg(1, 1, ""), receivedMsg(1, 3, ""),
+				receivedMsg(1, 2, ReorderedFault)},
+			map[int32][]int32{},
+		},
+		{
+			"duplicate",
+			[]faultEvent{sentMsg(1, 1), sentMsg(1, 2), receivedMsg(1, 1, ""), receivedMsg(1, 1, DuplicateFault),
+				receivedMsg(1, 2, "")},
+			map[int32][]int32{},
+		},
+		{
+			"duplicate of a reordered response",
+			[]faultEvent{sentMsg(1, 1), sentMsg(1, 2), receivedMsg(1, 2, ""), receivedMsg(1, 1, ReorderedFault),
+				receivedMsg(1, 1, DuplicateFault)},
+			map[int32][]int32{},
+		},
+		{
+			"reset message in place of the message",
+			[]faultEvent{sentMsg(1, 1), sentReset(1, 2), sentMsg(1, 3), receivedMsg(1, 1, ""), receivedMsg(1, 3, "")},
+			map[int32][]int32{},
+		},
+		{
+			"reset message at the end",
+			[]faultEvent{sentMsg(1, 1), sentReset(1, 2), receivedMsg(1, 1, "")},
+			map[int32][]int32{},
+		},
+		{
+			"streams tracked apart",
+			[]faultEvent{sentMsg(1, 1), sentMsg(2, 1), sentMsg(1, 2), sentMsg(2, 2), receivedMsg(2, 2, ""),
+				receivedMsg(1, 1, ""), receivedMsg(1, 2, ""), receivedMsg(2, 1, ReorderedFault),
+				receivedMsg(1, 2, DuplicateFault)},
+			map[int32][]int32{},
+		},
+		{
+			"lost on one stream only",
+			[]faultEvent{sentMsg(1, 1), sentMsg(2, 1), sentMsg(2, 2), receivedMsg(1, 1, ""), receivedMsg(2, 2, "")},
+			map[int32][]int32{2: {1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newFaultDetector()
+			for i, event := range tt.events {
+				if event.sent {
+					d.sent(event.stream, event.id, event.reset)
+					continue
+				}
+				if fault := d.received(event.stream, event.id); fault != event.fault {
+					t.Errorf("event %d: fault of %d/%d = %q, want %q", i, event.stream, event.id, fault, event.fault)
+				}
+			}
+			missing := d.missing()
+			for _, ids := range missing {
+				sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+			}
+			if !reflect.DeepEqual(missing, tt.missing) {
+				t.Errorf("missing = %v, want %v", missing, tt.missing)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package prober
+
+import (
+	"crypto/rand"
+	"errors"
+	"github.com/gorilla/websocket"
+	"github.com/richiMarchi/latency-tester/server/serialization/protobuf"
+	"google.golang.org/protobuf/proto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version of the protocol spoken by the client, the legacy handshake being the version 1
+const ProtocolVersion = 2
+
+// Behaviours of the server the client can request in the handshake
+const (
+	ResponseSizeBehaviour    = "response-size"
+	StreamsBehaviour         = "streams"
+	ProcessingDelayBehaviour = "processing-delay"
+	HopsBehaviour            = "hops"
+)
+
+// Patterns of the payloads
+const (
+	RandomPattern = "random"
+	ZerosPattern  = "zeros"
+)
+
+// Time the server has to reply to the handshake
+const handshakeTimeout = 10 * time.Second
+
+// Parse the payload pattern, random if empty
+func parsePayloadPattern(value string) (protobuf.PayloadPattern, error) {
+	switch value {
+	case "", RandomPattern:
+		return protobuf.PayloadPattern_RANDOM, nil
+	case ZerosPattern:
+		return protobuf.PayloadPattern_ZEROS, nil
+	}
+	return 0, errors.New("unknown pattern " + strconv.Quote(value) + ", allowed ones are random and zeros")
+}
+
+// Create a payload of the given size, random to avoid compression unless the zeros are requested
+func newPayload(size uint64, pattern protobuf.PayloadPattern) []byte {
+	payload := make([]byte, size)
+	if pattern == protobuf.PayloadPattern_RANDOM {
+		_, _ = rand.Read(payload)
+	}
+	return payload
+}
+
+// Return the behaviours of the server required by the configuration
+func (c *client) requiredBehaviours() []string {
+	var behaviours []string
+	if c.traceEntries != nil || len(c.streamSizes) > 0 {
+		behaviours = append(behaviours, ResponseSizeBehaviour)
+	}
+	if c.config.Streams > 1 {
+		behaviours = append(behaviours, StreamsBehaviour)
+	}
+	if c.processingDelay != nil {
+		behaviours = append(behaviours, ProcessingDelayBehaviour)
+	}
+	if c.config.Hops {
+		behaviours = append(behaviours, HopsBehaviour)
+	}
+	return behaviours
+}
+
+// Send the handshake on the new connection and check the reply of the server, failing if the server refuses the
+// session or does not speak the same protocol version
+func (c *client) handshake(conn *websocket.Conn) (*protobuf.HandshakeReply, error) {
+	request, _ := proto.Marshal(&protobuf.Handshake{
+		Version:         ProtocolVersion,
+		ResponseSize:    int32(c.config.ResponseBytes),
+		PayloadPattern:  c.pattern,
+		Behaviours:      c.requiredBehaviours(),
+		ProcessingDelay: c.processingDelay,
+	})
+	if err := conn.WriteMessage(websocket.BinaryMessage, request); err != nil {
+		return nil, errors.New("handshake: " + err.Error())
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(handshakeTimeout))
+	_, message, err := conn.ReadMessage()
+	if closeErr, closed := err.(*websocket.CloseError); closed {
+		return nil, errors.New("handshake: session refused by the server: " + closeErr.Text)
+	}
+	if err != nil {
+		return nil, errors.New("handshake: no reply from the server (" + err.Error() + "), it could be a build " +
+			"older than the protocol version " + strconv.Itoa(ProtocolVersion))
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+	reply := &protobuf.HandshakeReply{}
+	if err = proto.Unmarshal(message, reply); err != nil {
+		return nil, errors.New("handshake: invalid reply: " + err.Error())
+	}
+	if reply.Error != "" {
+		return nil, errors.New("handshake: session refused by the server: " + reply.Error)
+	}
+	if reply.Version != ProtocolVersion {
+		return nil, errors.New("handshake: the server speaks the protocol version " + strconv.Itoa(int(reply.Version)) +
+			", the client " + strconv.Itoa(ProtocolVersion))
+	}
+	var missing []string
+	for _, behaviour := range c.requiredBehaviours() {
+		supported := false
+		for _, capability := range reply.Capabilities {
+			if behaviour == capability {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			missing = append(missing, behaviour)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, errors.New("handshake: behaviours not supported by the server: " + strings.Join(missing, ", "))
+	}
+	return reply, nil
+}
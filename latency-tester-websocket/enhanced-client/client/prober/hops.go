@@ -0,0 +1,53 @@
+package prober
+
+import (
+	"github.com/richiMarchi/latency-tester/server/serialization/protobuf"
+	"time"
+)
+
+// Server the message went through in a relay chain
+type Hop struct {
+	Instance string
+	// Round trip time of the link from the previous hop (the client for the first one), -1 if not available
+	LinkRtt time.Duration
+	// Time spent by the message inside the server, excluding the upstream hops, -1 if not available
+	Residence time.Duration
+}
+
+// Return the hops of the message, the nearest to the client first. The link rtt is the time elapsed between the
+// previous hop forwarding the message and reading its response minus the whole time spent by the message from the
+// hop on, so every value is measured on a single clock and does not need the clocks to be synchronised
+func messageHops(hops []*protobuf.Hop, rtt time.Duration) []Hop {
+	result := make([]Hop, len(hops))
+	// The time between forwarding the message and reading its response, for the client the rtt itself
+	upstreamRtt := rtt
+	for i := range hops {
+		// The hops are appended on the way back, hence the nearest one is the last
+		hop := hops[len(hops)-1-i]
+		result[i] = Hop{Instance: hop.Instance, LinkRtt: -1, Residence: -1}
+		if hop.ReceiveTimestamp == nil || hop.SendTimestamp == nil {
+			upstreamRtt = -1
+			continue
+		}
+		total := hop.SendTimestamp.AsTime().Sub(hop.ReceiveTimestamp.AsTime())
+		if upstreamRtt >= 0 {
+			result[i].LinkRtt = upstreamRtt - total
+		}
+		if hop.ForwardTimestamp == nil || hop.ReplyTimestamp == nil {
+			result[i].Residence = total
+			upstreamRtt = -1
+			continue
+		}
+		upstreamRtt = hop.ReplyTimestamp.AsTime().Sub(hop.ForwardTimestamp.AsTime())
+		result[i].Residence = total - upstreamRtt
+	}
+	return result
+}
+
+// Format the time of the hop in milliseconds, empty if not available
+func hopColumn(d time.Duration) string {
+	if d < 0 {
+		return ""
+	}
+	return milliseconds(d)
+}
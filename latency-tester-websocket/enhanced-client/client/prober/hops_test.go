@@ -0,0 +1,61 @@
+package prober
+
+import (
+	"github.com/richiMarchi/latency-tester/server/serialization/protobuf"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Timestamp at the given milliseconds on the clock of a server
+func at(ms int64) *timestamppb.Timestamp {
+	return timestamppb.New(time.Unix(1600000000, 0).Add(time.Duration(ms) * time.Millisecond))
+}
+
+func TestMessageHops(t *testing.T) {
+	ms := time.Millisecond
+	// Last server of the chain, whose clock is far from the one of the relay
+	cloud := &protobuf.Hop{Instance: "cloud", ReceiveTimestamp: at(100), SendTimestamp: at(103)}
+	relay := &protobuf.Hop{Instance: "edge", ReceiveTimestamp: at(0), ForwardTimestamp: at(1), ReplyTimestamp: at(9),
+		SendTimestamp: at(10)}
+	tests := []struct {
+		name string
+		hops []*protobuf.Hop
+		rtt  time.Duration
+		want []Hop
+	}{
+		{"no hops", nil, 10 * ms, []Hop{}},
+		{"single server", []*protobuf.Hop{{Instance: "cloud", ReceiveTimestamp: at(0), SendTimestamp: at(2)}}, 10 * ms,
+			[]Hop{{Instance: "cloud", LinkRtt: 8 * ms, Residence: 2 * ms}}},
+		{"relay chain", []*protobuf.Hop{cloud, relay}, 14 * ms,
+			[]Hop{{Instance: "edge", LinkRtt: 4 * ms, Residence: 2 * ms},
+				{Instance: "cloud", LinkRtt: 5 * ms, Residence: 3 * ms}}},
+		{"rtt not measured", []*protobuf.Hop{cloud, relay}, -1,
+			[]Hop{{Instance: "edge", LinkRtt: -1, Residence: 2 * ms},
+				{Instance: "cloud", LinkRtt: 5 * ms, Residence: 3 * ms}}},
+		{"relay without the times of the message",
+			[]*protobuf.Hop{cloud, {Instance: "edge", ReplyTimestamp: at(9), SendTimestamp: at(10)}}, 14 * ms,
+			[]Hop{{Instance: "edge", LinkRtt: -1, Residence: -1},
+				{Instance: "cloud", LinkRtt: -1, Residence: 3 * ms}}},
+		{"relay without the upstream times",
+			[]*protobuf.Hop{cloud, {Instance: "edge", ReceiveTimestamp: at(0), SendTimestamp: at(10)}}, 14 * ms,
+			[]Hop{{Instance: "edge", LinkRtt: 4 * ms, Residence: 10 * ms},
+				{Instance: "cloud", LinkRtt: -1, Residence: 3 * ms}}},
+		{"three servers", []*protobuf.Hop{cloud,
+			{Instance: "regional", ReceiveTimestamp: at(50), ForwardTimestamp: at(51), ReplyTimestamp: at(59),
+				SendTimestamp: at(61)},
+			{Instance: "edge", ReceiveTimestamp: at(0), ForwardTimestamp: at(1), ReplyTimestamp: at(15),
+				SendTimestamp: at(16)}}, 20 * ms,
+			[]Hop{{Instance: "edge", LinkRtt: 4 * ms, Residence: 2 * ms},
+				{Instance: "regional", LinkRtt: 3 * ms, Residence: 3 * ms},
+				{Instance: "cloud", LinkRtt: 5 * ms, Residence: 3 * ms}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := messageHops(tt.hops, tt.rtt); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("hops = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
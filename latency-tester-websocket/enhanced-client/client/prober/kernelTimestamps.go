@@ -0,0 +1,243 @@
+package prober
+
+import (
+	"crypto/tls"
+	"errors"
+	"github.com/gorilla/websocket"
+	"golang.org/x/sys/unix"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Sources of the kernel timestamps of the messages
+const (
+	SoftwareTimestamps = "software"
+	HardwareTimestamps = "hardware"
+)
+
+// Message sent on a connection, identified by its stream and its ID
+type messageKey struct {
+	stream int32
+	id     int32
+}
+
+// TCP connection with SO_TIMESTAMPING enabled, keeping the kernel timestamps of the messages sent and of the last
+// data received
+type timestampingConn struct {
+	*net.TCPConn
+	raw      syscall.RawConn
+	hardware bool
+	mutex    sync.Mutex
+	// Bytes written since the timestamping was enabled, the kernel identifies the transmissions by their last byte
+	written uint32
+	// Message being written, whose transmission is identified by the last byte of its last write
+	sending      *messageKey
+	sentOffsets  map[messageKey]uint32
+	txTimestamps map[uint32]time.Time
+	lastRx       time.Time
+}
+
+// Enable the kernel timestamps of the data sent and received on the connection
+func enableTimestamping(conn *net.TCPConn, source string) (*timestampingConn, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+	flags := unix.SOF_TIMESTAMPING_TX_SOFTWARE | unix.SOF_TIMESTAMPING_RX_SOFTWARE | unix.SOF_TIMESTAMPING_SOFTWARE
+	if source == HardwareTimestamps {
+		flags = unix.SOF_TIMESTAMPING_TX_HARDWARE | unix.SOF_TIMESTAMPING_RX_HARDWARE | unix.SOF_TIMESTAMPING_RAW_HARDWARE
+	}
+	flags |= unix.SOF_TIMESTAMPING_OPT_ID | unix.SOF_TIMESTAMPING_OPT_TSONLY
+	var sockErr error
+	err = raw.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_TIMESTAMPING, flags)
+	})
+	if err == nil {
+		err = sockErr
+	}
+	if err != nil {
+		return nil, errors.New("SO_TIMESTAMPING: " + err.Error())
+	}
+	return &timestampingConn{
+		TCPConn:      conn,
+		raw:          raw,
+		hardware:     source == HardwareTimestamps,
+		sentOffsets:  make(map[messageKey]uint32),
+		txTimestamps: make(map[uint32]time.Time),
+	}, nil
+}
+
+func (tc *timestampingConn) Write(b []byte) (int, error) {
+	n, err := tc.TCPConn.Write(b)
+	tc.mutex.Lock()
+	tc.written += uint32(n)
+	if tc.sending != nil && n > 0 {
+		tc.sentOffsets[*tc.sending] = tc.written - 1
+	}
+	tc.mutex.Unlock()
+	return n, err
+}
+
+// Read the data together with the kernel timestamp of its reception
+func (tc *timestampingConn) Read(b []byte) (int, error) {
+	var n, oobn int
+	var readErr error
+	oob := make([]byte, 512)
+	err := tc.raw.Read(func(fd uintptr) bool {
+		n, oobn, _, _, readErr = unix.Recvmsg(int(fd), b, oob, 0)
+		return readErr != unix.EAGAIN
+	})
+	if err == nil {
+		err = readErr
+	}
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 && len(b) > 0 {
+		return 0, io.EOF
+	}
+	if timestamp, present := tc.parseTimestamp(oob[:oobn]); present {
+		tc.mutex.Lock()
+		tc.lastRx = timestamp
+		tc.mutex.Unlock()
+	}
+	return n, nil
+}
+
+// Associate the following writes to the message, until it is completely written. It has to be called before writing,
+// since the response can be received before the write returns
+func (tc *timestampingConn) startMessage(stream, id int32) {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+	tc.sending = &messageKey{stream: stream, id: id}
+}
+
+func (tc *timestampingConn) endMessage() {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+	tc.sending = nil
+}
+
+// Return and forget the kernel transmission timestamp of the message, false if it has not been reported
+func (tc *timestampingConn) takeTxTimestamp(stream, id int32) (time.Time, bool) {
+	tc.drainErrorQueue()
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+	key := messageKey{stream: stream, id: id}
+	offset, sent := tc.sentOffsets[key]
+	if !sent {
+		return time.Time{}, false
+	}
+	timestamp, present := tc.txTimestamps[offset]
+	delete(tc.sentOffsets, key)
+	delete(tc.txTimestamps, offset)
+	return timestamp, present
+}
+
+// Return the kernel timestamp of the last data received
+func (tc *timestampingConn) rxTimestamp() (time.Time, bool) {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+	return tc.lastRx, !tc.lastRx.IsZero()
+}
+
+// Read the transmission timestamps queued by the kernel on the error queue of the socket
+func (tc *timestampingConn) drainErrorQueue() {
+	buf := make([]byte, 1)
+	oob := make([]byte, 512)
+	_ = tc.raw.Control(func(fd uintptr) {
+		for {
+			_, oobn, _, _, err := unix.Recvmsg(int(fd), buf, oob, unix.MSG_ERRQUEUE|unix.MSG_DONTWAIT)
+			if err != nil {
+				return
+			}
+			timestamp, present := tc.parseTimestamp(oob[:oobn])
+			offset, sent := parseTxOffset(oob[:oobn])
+			if !present || !sent {
+				continue
+			}
+			tc.mutex.Lock()
+			tc.txTimestamps[offset] = timestamp
+			tc.mutex.Unlock()
+		}
+	})
+}
+
+// Return the timestamp of the configured source carried by the control messages
+func (tc *timestampingConn) parseTimestamp(oob []byte) (time.Time, bool) {
+	messages, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return time.Time{}, false
+	}
+	for _, m := range messages {
+		if m.Header.Level != unix.SOL_SOCKET || m.Header.Type != unix.SCM_TIMESTAMPING ||
+			len(m.Data) < int(unsafe.Sizeof(unix.ScmTimestamping{})) {
+			continue
+		}
+		timestamps := (*unix.ScmTimestamping)(unsafe.Pointer(&m.Data[0]))
+		// The software timestamp is the first one, the raw hardware one is the third one
+		ts := timestamps.Ts[0]
+		if tc.hardware {
+			ts = timestamps.Ts[2]
+		}
+		if ts.Sec == 0 && ts.Nsec == 0 {
+			return time.Time{}, false
+		}
+		return time.Unix(int64(ts.Sec), int64(ts.Nsec)), true
+	}
+	return time.Time{}, false
+}
+
+// Return the offset of the last byte of the transmission the error queue message refers to
+func parseTxOffset(oob []byte) (uint32, bool) {
+	messages, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return 0, false
+	}
+	for _, m := range messages {
+		if !(m.Header.Level == unix.SOL_IP && m.Header.Type == unix.IP_RECVERR) &&
+			!(m.Header.Level == unix.SOL_IPV6 && m.Header.Type == unix.IPV6_RECVERR) ||
+			len(m.Data) < int(unsafe.Sizeof(unix.SockExtendedErr{})) {
+			continue
+		}
+		extendedErr := (*unix.SockExtendedErr)(unsafe.Pointer(&m.Data[0]))
+		if extendedErr.Origin == unix.SO_EE_ORIGIN_TIMESTAMPING && extendedErr.Info == unix.SCM_TSTAMP_SND {
+			return extendedErr.Data, true
+		}
+	}
+	return 0, false
+}
+
+// Return the timestamping connection carrying the websocket connection, nil if the kernel timestamps are disabled
+func (c *client) timestampingConnOf(conn *websocket.Conn) *timestampingConn {
+	if c.config.KernelTimestamps == "" || conn == nil {
+		return nil
+	}
+	underlying := conn.UnderlyingConn()
+	if c.config.Tls {
+		underlying = getConnFromTLSConn(underlying.(*tls.Conn))
+	}
+	tc, _ := underlying.(*timestampingConn)
+	return tc
+}
+
+// Format the kernel timestamps of the message as the values of the kernel columns, empty if not available
+func kernelColumns(tx, rx time.Time) []string {
+	columns := []string{"", "", ""}
+	if !tx.IsZero() {
+		columns[0] = strconv.FormatInt(tx.UnixNano(), 10)
+	}
+	if !rx.IsZero() {
+		columns[1] = strconv.FormatInt(rx.UnixNano(), 10)
+	}
+	if !tx.IsZero() && !rx.IsZero() {
+		columns[2] = strconv.FormatFloat(float64(rx.Sub(tx).Nanoseconds())/float64(time.Millisecond.Nanoseconds()),
+			'f', -1, 64)
+	}
+	return columns
+}
@@ -0,0 +1,242 @@
+package prober
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/sys/unix"
+	"log"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// Kinds of priority of the sender and reader threads
+const (
+	RealtimePriority = "rt"
+	NicePriority     = "nice"
+)
+
+// Value of the gc option disabling the garbage collector during the run
+const GcOff = "off"
+
+// SCHED_FIFO scheduling policy, missing from the unix package
+const schedFifo = 1
+
+// Priority requested for the sender and reader threads, a SCHED_FIFO priority or a nice value
+type threadPriority struct {
+	kind  string
+	value int
+}
+
+// Parse a comma separated list of CPUs
+func parseCpus(value string) ([]int, error) {
+	var cpus []int
+	if value == "" {
+		return cpus, nil
+	}
+	for _, cpu := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(cpu))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid CPU %q", cpu)
+		}
+		cpus = append(cpus, n)
+	}
+	return cpus, nil
+}
+
+// Parse a priority expressed as rt:N (SCHED_FIFO priority from 1 to 99) or nice:N (nice value from -20 to 19)
+func parseThreadPriority(value string) (*threadPriority, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid priority %q, expected rt:N or nice:N", value)
+	}
+	n, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid priority %q, expected rt:N or nice:N", value)
+	}
+	switch parts[0] {
+	case RealtimePriority:
+		if n < 1 || n > 99 {
+			return nil, errors.New("the real-time priority has to be between 1 and 99")
+		}
+	case NicePriority:
+		if n < -20 || n > 19 {
+			return nil, errors.New("the nice value has to be between -20 and 19")
+		}
+	default:
+		return nil, fmt.Errorf("unknown priority %q, allowed ones are rt and nice", parts[0])
+	}
+	return &threadPriority{kind: parts[0], value: n}, nil
+}
+
+// Parse the gc option, off or the percentage of heap growth triggering a collection
+func parseGcPercent(value string) (int, error) {
+	if value == GcOff {
+		return -1, nil
+	}
+	percent, err := strconv.Atoi(value)
+	if err != nil || percent <= 0 {
+		return 0, fmt.Errorf("invalid value %q, expected off or a percentage", value)
+	}
+	return percent, nil
+}
+
+// True if any option of the low-noise mode is requested, recording the garbage collection pauses
+func (c *client) lowNoise() bool {
+	return len(c.cpus) > 0 || c.priority != nil || c.config.Gc != ""
+}
+
+// Lock the calling goroutine to its thread, pinning it to the next requested CPU and setting the requested priority.
+// The thread is never unlocked, so that it is terminated with the goroutine instead of being reused by the runtime
+func (c *client) lockThread() {
+	if len(c.cpus) == 0 && c.priority == nil {
+		return
+	}
+	runtime.LockOSThread()
+	if len(c.cpus) > 0 {
+		var set unix.CPUSet
+		cpu := c.cpus[int(atomic.AddUint32(&c.nextCpu, 1)-1)%len(c.cpus)]
+		set.Set(cpu)
+		// The pid 0 refers to the calling thread
+		if err := unix.SchedSetaffinity(0, &set); err != nil {
+			warnOnce(&c.affinityWarning, "WARNING: thread not pinned to CPU "+strconv.Itoa(cpu)+": "+err.Error())
+		}
+	}
+	if c.priority != nil {
+		if err := setThreadPriority(c.priority); err != nil {
+			warnOnce(&c.priorityWarning, "WARNING: thread priority not set: "+err.Error())
+		}
+	}
+}
+
+// Set the priority of the calling thread
+func setThreadPriority(priority *threadPriority) error {
+	if priority.kind == NicePriority {
+		return unix.Setpriority(unix.PRIO_PROCESS, unix.Gettid(), priority.value)
+	}
+	param := struct{ priority int32 }{int32(priority.value)}
+	_, _, errno := unix.Syscall(unix.SYS_SCHED_SETSCHEDULER, 0, schedFifo, uintptr(unsafe.Pointer(&param)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Log the message the first time only, the threads share the same failures
+func warnOnce(once *sync.Once, message string) {
+	once.Do(func() {
+		log.Println(message)
+	})
+}
+
+// Records the pauses of the garbage collections completed during the run
+type gcMonitor struct {
+	file    *os.File
+	numGc   int64
+	cycles  chan struct{}
+	stopped int32
+	done    chan struct{}
+}
+
+// Create the file of the pauses and start recording them
+func startGcMonitor(filename string) (*gcMonitor, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	file.WriteString("#pause-end-timestamp,pause,gc-number\n")
+	var stats debug.GCStats
+	debug.ReadGCStats(&stats)
+	m := &gcMonitor{
+		file:   file,
+		numGc:  stats.NumGC,
+		cycles: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	m.arm()
+	go m.record()
+	return m, nil
+}
+
+// Allocate an object whose finalizer signals the end of the next collection and arms another one, so that the
+// collections are detected without polling the runtime
+func (m *gcMonitor) arm() {
+	sentinel := new([16]byte)
+	runtime.SetFinalizer(sentinel, func(*[16]byte) {
+		select {
+		case m.cycles <- struct{}{}:
+		default:
+		}
+		if atomic.LoadInt32(&m.stopped) == 0 {
+			m.arm()
+		}
+	})
+}
+
+func (m *gcMonitor) record() {
+	defer close(m.done)
+	for range m.cycles {
+		m.writePauses()
+		if atomic.LoadInt32(&m.stopped) != 0 {
+			return
+		}
+	}
+}
+
+// Write the pauses of the collections completed since the last write, the oldest first
+func (m *gcMonitor) writePauses() {
+	var stats debug.GCStats
+	debug.ReadGCStats(&stats)
+	newGcs := int(stats.NumGC - m.numGc)
+	if newGcs > len(stats.Pause) {
+		log.Println("WARNING:", newGcs-len(stats.Pause), "garbage collection pauses not recorded")
+		newGcs = len(stats.Pause)
+	}
+	for i := newGcs - 1; i >= 0; i-- {
+		m.file.WriteString(strconv.FormatInt(stats.PauseEnd[i].UnixNano(), 10) + "," +
+			strconv.FormatFloat(float64(stats.Pause[i].Nanoseconds())/float64(time.Millisecond.Nanoseconds()), 'f', -1, 64) +
+			"," + strconv.FormatInt(stats.NumGC-int64(i), 10) + "\n")
+	}
+	m.numGc = stats.NumGC
+}
+
+// Record the pauses not written yet and close the file
+func (m *gcMonitor) stop() {
+	atomic.StoreInt32(&m.stopped, 1)
+	select {
+	case m.cycles <- struct{}{}:
+	default:
+	}
+	<-m.done
+	m.file.Close()
+}
+
+// Tune the garbage collector for the run and start recording its pauses, returning the function restoring it
+func (c *client) startLowNoise() (func(), error) {
+	if !c.lowNoise() {
+		return func() {}, nil
+	}
+	// Start from a clean heap, so that the collections during the run are as few as possible
+	runtime.GC()
+	monitor, err := startGcMonitor(c.config.LogFile + "_gc-pauses.csv")
+	if err != nil {
+		return nil, err
+	}
+	if c.config.Gc == "" {
+		return monitor.stop, nil
+	}
+	previous := debug.SetGCPercent(c.gcPercent)
+	return func() {
+		debug.SetGCPercent(previous)
+		monitor.stop()
+	}, nil
+}
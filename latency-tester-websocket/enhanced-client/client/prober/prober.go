@@ -0,0 +1,548 @@
+// Package prober measures the application-level round trip time towards a latency-tester server over WebSocket,
+// storing the results in files and optionally publishing them on a channel. It is used by the client command,
+// and it can be imported to run the measurements in-process.
+package prober
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/richiMarchi/latency-tester/enhanced-client/client/hoststats"
+	"github.com/richiMarchi/latency-tester/enhanced-client/client/sink"
+	"github.com/richiMarchi/latency-tester/server/serialization/protobuf"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Measurement towards a server
+type Prober interface {
+	// Open the connections towards the server and create the output files
+	Connect() error
+	// Send the messages until all of them are sent or the context is cancelled
+	Run(ctx context.Context) error
+	// Channel of the per-message results, closed at the end of Run. If requested, it has to be requested before Run
+	// and consumed by the caller, otherwise the results are only stored in the files. The results the caller is too
+	// late to receive are dropped from the channel, not from the files, and counted at the end of Run
+	Results() <-chan Result
+}
+
+// Parameters of a measurement, the same ones accepted by the client as flags. In the configuration files, the keys are
+// the names of the flags, plus the address of the server
+type Config struct {
+	Address          string `yaml:"address"`
+	Reps             uint64 `yaml:"reps"`
+	LogFile          string `yaml:"log"`
+	Format           string `yaml:"format"`     // csv, jsonl or protobuf
+	RotateSize       uint64 `yaml:"rotateSize"` // in megabytes
+	RotateEvery      string `yaml:"rotateEvery"`
+	RequestBytes     uint64 `yaml:"requestPayload"`
+	ResponseBytes    uint64 `yaml:"responsePayload"`
+	PayloadPattern   string `yaml:"payloadPattern"` // random or zeros
+	ProcessingDelay  string `yaml:"processingDelay"`
+	Interval         uint64 `yaml:"interval"` // in milliseconds
+	Tls              bool   `yaml:"tls"`
+	TracerouteIp     string `yaml:"traceroute"`
+	TcpStats         bool   `yaml:"tcpStats"`
+	HostStats        uint64 `yaml:"hostStats"`        // sampling period in milliseconds, disabled if 0
+	KernelTimestamps string `yaml:"kernelTimestamps"` // software or hardware, disabled if empty
+	ServerTimestamps bool   `yaml:"serverTimestamps"`
+	ServerInstance   bool   `yaml:"serverInstance"`
+	Hops             bool   `yaml:"hops"`
+	DetectFaults     bool   `yaml:"detectFaults"`
+	InjectedFaults   string `yaml:"injectedFaults"` // faults log of the server
+	Cpus             string `yaml:"cpus"`           // comma separated
+	Priority         string `yaml:"priority"`       // rt:N or nice:N
+	Gc               string `yaml:"gc"`             // off or a percentage
+	SrcPort          int    `yaml:"srcPort"`
+	Warmup           string `yaml:"warmup"`
+	Cooldown         string `yaml:"cooldown"`
+	IdleGaps         string `yaml:"idleGaps"`
+	Burst            uint64 `yaml:"burst"`
+	ReconnectEvery   uint64 `yaml:"reconnectEvery"`
+	TlsResumption    bool   `yaml:"tlsResumption"`
+	Connections      int    `yaml:"connections"`
+	SplitConnections bool   `yaml:"splitConnections"`
+	Streams          int    `yaml:"streams"`
+	StreamSizes      string `yaml:"streamSizes"`
+	TraceFile        string `yaml:"trace"`
+	Profile          string `yaml:"profile"`
+	// If true, the result of each message is printed on the standard output as a JSON line
+	JsonLines bool `yaml:"jsonLines"`
+	// If true, the configuration and the rtt of each message are printed on the standard output
+	Verbose bool `yaml:"-"`
+}
+
+// Outcome of a message
+type Result struct {
+	Connection      int
+	Stream          int32
+	Id              int32 // 0 for the message sent after a connection reset
+	ClientTimestamp time.Time
+	ServerTimestamp time.Time
+	Rtt             time.Duration // -1 for the message sent after a connection reset
+	Phase           string
+	// Kernel timestamps of the transmission of the message and of the reception of its response, zero if not available
+	KernelTxTimestamp time.Time
+	KernelRxTimestamp time.Time
+	// Processing time applied by the server, zero if not requested
+	ProcessingDelay time.Duration
+	// Times the server received the message, processed it and sent the response, zero if not reported
+	ServerReceiveTimestamp   time.Time
+	ProcessingStartTimestamp time.Time
+	ProcessingEndTimestamp   time.Time
+	ServerSendTimestamp      time.Time
+	// Time the response was received
+	ReceiveTimestamp time.Time
+	// Instance of the server that replied, empty if not reported
+	ServerInstance string
+	// Servers the message went through if requested, the nearest to the client first
+	Hops []Hop
+}
+
+type client struct {
+	config                       Config
+	warmupWindow, cooldownWindow exclusionWindow
+	sendStart                    time.Time
+	idleGapList                  []time.Duration
+	profile                      *trafficProfile
+	traceEntries                 []traceEntry
+	// Payload sizes of the streams, in turn, if they differ from the request and response ones
+	streamSizes []streamSize
+	// TLS sessions shared among the connections when the resumption is enabled
+	tlsSessionCache tls.ClientSessionCache
+	rotation        sink.Rotation
+	pattern         protobuf.PayloadPattern
+	processingDelay *protobuf.ProcessingDelay
+	// Identity of the server, as replied to the handshake
+	serverIdentity *protobuf.ServerIdentity
+	sessions       []*session
+	// Closed to stop reading the socket statistics
+	ssStop  chan struct{}
+	results chan Result
+	// Results dropped from the channel since the caller was late to receive them, accessed atomically
+	droppedResults uint64
+	// Low-noise mode, the CPUs are assigned in turn to the sender and reader threads
+	cpus            []int
+	nextCpu         uint32
+	priority        *threadPriority
+	gcPercent       int
+	affinityWarning sync.Once
+	priorityWarning sync.Once
+	// Faults detected by all the sessions, by fault
+	faultCounts struct {
+		sync.Mutex
+		counts map[string]int
+	}
+}
+
+// Return the configuration with the default values of the client flags
+func DefaultConfig() Config {
+	return Config{
+		LogFile:       "/execdir/log",
+		Format:        sink.CsvFormat,
+		RequestBytes:  64,
+		ResponseBytes: 64,
+		Interval:      1000,
+		Burst:         10,
+		Connections:   1,
+		Streams:       1,
+	}
+}
+
+// Parse the client command line arguments, the flags followed by the server address. If a configuration file is
+// given, the flags explicitly set override its values
+func ConfigFromArgs(args []string) (Config, error) {
+	config := DefaultConfig()
+	var configFile string
+	flags := newFlagSet(&config, &configFile)
+	if err := flags.Parse(args); err != nil {
+		return config, err
+	}
+	if configFile != "" {
+		config = DefaultConfig()
+		if err := loadConfigFile(configFile, &config); err != nil {
+			return config, errors.New("config: " + err.Error())
+		}
+		if err := newFlagSet(&config, &configFile).Parse(args); err != nil {
+			return config, err
+		}
+	}
+	if flags.NArg() > 0 {
+		config.Address = flags.Arg(0)
+	}
+	return config, nil
+}
+
+// Return the client flags, bound to the given configuration
+func newFlagSet(config *Config, configFile *string) *flag.FlagSet {
+	flags := flag.NewFlagSet("client", flag.ContinueOnError)
+	flags.StringVar(configFile, "config", "", "YAML or JSON file describing the probe, overridden by the flags")
+	flags.Uint64Var(&config.Reps, "reps", config.Reps, "number of repetitions")
+	flags.StringVar(&config.LogFile, "log", config.LogFile, "file to store latency numbers")
+	flags.StringVar(&config.Format, "format", config.Format, "format of the results file (csv, jsonl or protobuf)")
+	flags.Uint64Var(&config.RotateSize, "rotateSize", config.RotateSize,
+		"megabytes after which the results file is rotated (0 = never)")
+	flags.StringVar(&config.RotateEvery, "rotateEvery", config.RotateEvery,
+		"time after which the results file is rotated (e.g. 1h)")
+	flags.Uint64Var(&config.RequestBytes, "requestPayload", config.RequestBytes, "bytes of the payload")
+	flags.Uint64Var(&config.ResponseBytes, "responsePayload", config.ResponseBytes, "bytes of the response payload")
+	flags.StringVar(&config.PayloadPattern, "payloadPattern", config.PayloadPattern,
+		"pattern of the request and response payloads (random or zeros)")
+	flags.StringVar(&config.ProcessingDelay, "processingDelay", config.ProcessingDelay,
+		"processing time emulated by the server (fixed:MS, uniform:MIN/MAX, lognormal:MEDIAN/SIGMA, busy:ITERATIONS)")
+	flags.Uint64Var(&config.Interval, "interval", config.Interval, "send interval time (ms)")
+	flags.BoolVar(&config.Tls, "tls", config.Tls, "true if TLS enabled")
+	flags.StringVar(&config.TracerouteIp, "traceroute", config.TracerouteIp, "traceroute ip if requested")
+	flags.BoolVar(&config.TcpStats, "tcpStats", config.TcpStats, "true if TCP Stats requested")
+	flags.Uint64Var(&config.HostStats, "hostStats", config.HostStats,
+		"sampling period of the host load statistics (ms, 0 = disabled)")
+	flags.StringVar(&config.KernelTimestamps, "kernelTimestamps", config.KernelTimestamps,
+		"kernel timestamps of the messages to record with SO_TIMESTAMPING (software or hardware)")
+	flags.BoolVar(&config.ServerTimestamps, "serverTimestamps", config.ServerTimestamps,
+		"true if the rtt has to be decomposed into uplink, server residence and downlink")
+	flags.BoolVar(&config.ServerInstance, "serverInstance", config.ServerInstance,
+		"true if the instance of the server replying to each message has to be stored")
+	flags.BoolVar(&config.Hops, "hops", config.Hops,
+		"true if the residence and link times of each server of a relay chain have to be stored")
+	flags.BoolVar(&config.DetectFaults, "detectFaults", config.DetectFaults,
+		"true if the missing, duplicate, reordered and corrupted responses and the resets have to be stored")
+	flags.StringVar(&config.InjectedFaults, "injectedFaults", config.InjectedFaults,
+		"faults log of the server to cross-check the detected faults with, enabling the detection")
+	flags.StringVar(&config.Cpus, "cpus", config.Cpus,
+		"comma separated CPUs the sender and reader threads are pinned to, in turn")
+	flags.StringVar(&config.Priority, "priority", config.Priority,
+		"priority of the sender and reader threads (rt:N for SCHED_FIFO, nice:N)")
+	flags.StringVar(&config.Gc, "gc", config.Gc, "garbage collector during the run (off or the GOGC percentage)")
+	flags.IntVar(&config.SrcPort, "srcPort", config.SrcPort, "client source port")
+	flags.StringVar(&config.Warmup, "warmup", config.Warmup,
+		"warm-up window to flag, as messages (e.g. 10) or duration (e.g. 5s)")
+	flags.StringVar(&config.Cooldown, "cooldown", config.Cooldown,
+		"cool-down window to flag, as messages (e.g. 10) or duration (e.g. 5s)")
+	flags.StringVar(&config.IdleGaps, "idleGaps", config.IdleGaps,
+		"comma separated idle gaps (ms) between bursts, enables the idle-gap mode")
+	flags.Uint64Var(&config.Burst, "burst", config.Burst, "messages sent in each burst of the idle-gap mode")
+	flags.Uint64Var(&config.ReconnectEvery, "reconnectEvery", config.ReconnectEvery,
+		"messages sent on each connection before opening a new one (0 = never)")
+	flags.BoolVar(&config.TlsResumption, "tlsResumption", config.TlsResumption,
+		"true if reconnections resume the previous TLS session")
+	flags.IntVar(&config.Connections, "connections", config.Connections,
+		"parallel websocket connections, each one sending -reps messages")
+	flags.BoolVar(&config.SplitConnections, "splitConnections", config.SplitConnections,
+		"true if each connection stores its results in its own files")
+	flags.IntVar(&config.Streams, "streams", config.Streams, "logical streams multiplexed over each websocket connection")
+	flags.StringVar(&config.StreamSizes, "streamSizes", config.StreamSizes,
+		"comma separated request/response payload sizes of the streams in bytes, assigned in turn to the streams of "+
+			"every connection or, with a single stream, to the connections")
+	flags.StringVar(&config.TraceFile, "trace", config.TraceFile,
+		"trace file to replay (offset-ms,request-bytes,response-bytes lines)")
+	flags.StringVar(&config.Profile, "profile", config.Profile,
+		"traffic profile replacing the send interval (burst:N/T, onoff:I/ON/OFF, ramp:FROM/TO)")
+	flags.BoolVar(&config.JsonLines, "jsonLines", config.JsonLines,
+		"true if the result of each message is printed on stdout as a JSON line")
+	return flags
+}
+
+// Load the configuration from a YAML or JSON file, rejecting unknown keys
+func loadConfigFile(filename string, config *Config) error {
+	file, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	// JSON documents are valid YAML ones too
+	return yaml.UnmarshalStrict(file, config)
+}
+
+// Validate the configuration and return the prober implementing it
+func New(config Config) (Prober, error) {
+	c := &client{config: config, tlsSessionCache: tls.NewLRUClientSessionCache(1)}
+	if c.config.Address == "" {
+		return nil, errors.New("server address required")
+	}
+	var err error
+	if _, err = sink.Extension(c.config.Format); err != nil {
+		return nil, errors.New("format: " + err.Error())
+	}
+	if c.config.KernelTimestamps != "" && c.config.KernelTimestamps != SoftwareTimestamps &&
+		c.config.KernelTimestamps != HardwareTimestamps {
+		return nil, errors.New("kernelTimestamps: unknown source " + strconv.Quote(c.config.KernelTimestamps) +
+			", allowed ones are software and hardware")
+	}
+	if c.pattern, err = parsePayloadPattern(c.config.PayloadPattern); err != nil {
+		return nil, errors.New("payloadPattern: " + err.Error())
+	}
+	if c.processingDelay, err = parseProcessingDelay(c.config.ProcessingDelay); err != nil {
+		return nil, errors.New("processingDelay: " + err.Error())
+	}
+	if c.config.InjectedFaults != "" {
+		c.config.DetectFaults = true
+	}
+	c.faultCounts.counts = make(map[string]int)
+	if c.cpus, err = parseCpus(c.config.Cpus); err != nil {
+		return nil, errors.New("cpus: " + err.Error())
+	}
+	if c.priority, err = parseThreadPriority(c.config.Priority); err != nil {
+		return nil, errors.New("priority: " + err.Error())
+	}
+	if c.config.Gc != "" {
+		if c.gcPercent, err = parseGcPercent(c.config.Gc); err != nil {
+			return nil, errors.New("gc: " + err.Error())
+		}
+	}
+	c.rotation.Size = int64(c.config.RotateSize) * 1024 * 1024
+	if c.config.RotateEvery != "" {
+		if c.rotation.Every, err = time.ParseDuration(c.config.RotateEvery); err != nil || c.rotation.Every <= 0 {
+			return nil, errors.New("rotateEvery: invalid duration " + strconv.Quote(c.config.RotateEvery))
+		}
+	}
+	if c.warmupWindow, err = parseExclusionWindow(c.config.Warmup); err != nil {
+		return nil, errors.New("warmup: " + err.Error())
+	}
+	if c.cooldownWindow, err = parseExclusionWindow(c.config.Cooldown); err != nil {
+		return nil, errors.New("cooldown: " + err.Error())
+	}
+	if c.idleGapList, err = parseIdleGaps(c.config.IdleGaps); err != nil {
+		return nil, errors.New("idleGaps: " + err.Error())
+	}
+	if len(c.idleGapList) > 0 {
+		if c.config.Burst == 0 {
+			return nil, errors.New("burst: at least one message per burst is required")
+		}
+		// One burst before each gap and a last one after it
+		c.config.Reps = c.config.Burst * uint64(len(c.idleGapList)+1)
+	}
+	if c.profile, err = parseTrafficProfile(c.config.Profile); err != nil {
+		return nil, errors.New("profile: " + err.Error())
+	}
+	if c.profile != nil && c.profile.kind == RampProfile && c.config.Reps == 0 {
+		return nil, errors.New("profile: the ramp profile requires the number of repetitions")
+	}
+	if c.profile != nil && len(c.idleGapList) > 0 {
+		return nil, errors.New("profile: traffic profiles cannot be combined with the idle-gap mode")
+	}
+	if c.config.TraceFile != "" {
+		if c.profile != nil || len(c.idleGapList) > 0 {
+			return nil, errors.New("trace: the trace replay cannot be combined with traffic profiles or the idle-gap mode")
+		}
+		if c.traceEntries, err = loadTrace(c.config.TraceFile); err != nil {
+			return nil, errors.New("trace: " + err.Error())
+		}
+		c.config.Reps = uint64(len(c.traceEntries))
+	}
+	if c.config.Connections < 1 {
+		return nil, errors.New("connections: at least one connection is required")
+	}
+	if c.config.Streams < 1 {
+		return nil, errors.New("streams: at least one stream is required")
+	}
+	if c.config.Streams > 1 && (c.config.ReconnectEvery != 0 || len(c.idleGapList) > 0) {
+		return nil, errors.New("streams: multiple streams cannot be combined with reconnections or the idle-gap mode")
+	}
+	if c.streamSizes, err = parseStreamSizes(c.config.StreamSizes); err != nil {
+		return nil, errors.New("streamSizes: " + err.Error())
+	}
+	if len(c.streamSizes) > 0 && c.traceEntries != nil {
+		return nil, errors.New("streamSizes: the stream sizes cannot be combined with the trace replay")
+	}
+	if c.config.Reps == 0 && !c.cooldownWindow.isEmpty() {
+		log.Println("WARNING: cool-down window ignored, the number of repetitions is not defined")
+	}
+	if c.config.ReconnectEvery != 0 && c.config.SrcPort != 0 {
+		log.Println("WARNING: reconnecting from a fixed source port could fail while the previous connection is in TIME_WAIT")
+	}
+	return c, nil
+}
+
+func (c *client) Results() <-chan Result {
+	if c.results == nil {
+		c.results = make(chan Result, 64)
+	}
+	return c.results
+}
+
+func (c *client) Connect() error {
+	if c.config.Verbose {
+		c.printLogs()
+	}
+
+	// Create the sessions, with the files they store the results in
+	c.sessions = make([]*session, c.config.Connections)
+	var out *outputFiles
+	for i := range c.sessions {
+		if out == nil || c.config.SplitConnections {
+			var err error
+			if out, err = c.createOutputFiles(c.sessionPrefix(i + 1)); err != nil {
+				c.closeOutputFiles()
+				return err
+			}
+		}
+		c.sessions[i] = newSession(c, i+1, out)
+	}
+
+	if c.config.TracerouteIp != "" {
+		tracerouteFile, tracerouteFileErr := os.Create(c.config.LogFile + "_traceroute")
+		if tracerouteFileErr != nil {
+			c.closeOutputFiles()
+			return tracerouteFileErr
+		}
+
+		log.Println("Starting traceroute to", c.config.TracerouteIp+"...")
+		customTraceroute(c.config.TracerouteIp, tracerouteFile)
+		tracerouteFile.Close()
+		log.Println("Traceroute completed!")
+		log.Println()
+	}
+
+	// Create websocket communication channels
+	for i, s := range c.sessions {
+		var setup connectionSetup
+		var err error
+		if s.conn, setup, err = c.connectWithSetup(s.sourcePort()); err != nil {
+			for _, opened := range c.sessions[:i] {
+				opened.conn.Close()
+			}
+			c.closeOutputFiles()
+			return err
+		}
+		if c.config.ReconnectEvery != 0 {
+			s.coldConnections.Store(int32(1), setup)
+		}
+		if c.serverIdentity == nil {
+			c.serverIdentity = setup.server
+		}
+	}
+	if c.config.Verbose && c.serverIdentity != nil {
+		fmt.Println("Server:\t\t\t", c.serverIdentity.Hostname, "("+c.serverIdentity.Software+")")
+		if c.serverIdentity.Instance != "" {
+			fmt.Println("Server instance:\t", c.serverIdentity.Instance)
+		}
+		if c.serverIdentity.Pod != "" || c.serverIdentity.Node != "" || c.serverIdentity.Region != "" {
+			fmt.Println("Server pod:\t\t", c.serverIdentity.Pod)
+			fmt.Println("Server node:\t\t", c.serverIdentity.Node)
+			fmt.Println("Server region:\t\t", c.serverIdentity.Region)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+func (c *client) Run(ctx context.Context) error {
+	defer c.closeOutputFiles()
+	if c.results != nil {
+		defer close(c.results)
+	}
+	for _, s := range c.sessions {
+		defer s.conn.Close()
+	}
+
+	restore, err := c.startLowNoise()
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	if c.config.HostStats != 0 {
+		recorder, err := hoststats.Start(c.config.LogFile+"_host-stats.csv",
+			time.Duration(c.config.HostStats)*time.Millisecond)
+		if err != nil {
+			return err
+		}
+		defer recorder.Stop()
+	}
+
+	// If explicitly requested tcp stats files, all created before starting any go routine
+	var tcpStatsFiles []*os.File
+	if c.config.TcpStats {
+		for _, s := range c.sessions {
+			// The socket statistics are always stored per connection
+			tcpStatsPrefix := c.config.LogFile
+			if c.config.Connections > 1 {
+				tcpStatsPrefix += "-c" + strconv.Itoa(s.id)
+			}
+			tcpStats, tcpStatsFileErr := os.Create(tcpStatsPrefix + "_tcp-stats.csv")
+			if tcpStatsFileErr != nil {
+				for _, created := range tcpStatsFiles {
+					created.Close()
+				}
+				return tcpStatsFileErr
+			}
+			tcpStats.WriteString("#timestamp,message-id,state,ca_state,retransmits,probes,backoff,options,pad_cgo_0-0," +
+				"pad_cgo_0-1,rto,ato,snd_mss,rcv_mss,unacked,sacked,lost,retrans,fackets,last_data_sent,last_ack_sent," +
+				"last_data_recv,last_ack_recv,pmtu,rcv_ssthresh,rtt,rttvar,snd_ssthresh,snd_cwnd,advmss,reordering,rcv_rtt," +
+				"rcv_space,total_retrans\n")
+			tcpStatsFiles = append(tcpStatsFiles, tcpStats)
+		}
+	}
+
+	// Parallel read dispatchers
+	c.sendStart = getTimestamp()
+	for _, s := range c.sessions {
+		go s.readDispatcher()
+	}
+
+	// TCP stats handlers
+	var wg sync.WaitGroup
+	c.ssStop = make(chan struct{})
+	for i, tcpStats := range tcpStatsFiles {
+		wg.Add(1)
+		go c.getSocketStats(c.sessions[i], tcpStats, &wg)
+	}
+
+	// Start making requests
+	var senders sync.WaitGroup
+	errs := make(chan error, len(c.sessions))
+	for _, s := range c.sessions {
+		senders.Add(1)
+		go func(s *session) {
+			defer senders.Done()
+			errs <- s.run(ctx)
+		}(s)
+	}
+	senders.Wait()
+	close(errs)
+
+	// Stop all go routines
+	close(c.ssStop)
+
+	// Wait for the go routines to complete their job
+	for _, s := range c.sessions {
+		<-s.done
+	}
+	wg.Wait()
+	if c.config.DetectFaults {
+		for _, s := range c.sessions {
+			s.recordMissing()
+		}
+		if c.config.InjectedFaults != "" {
+			if err := c.crossCheckFaults(c.sendStart, getTimestamp()); err != nil {
+				return err
+			}
+		}
+	}
+	if dropped := atomic.LoadUint64(&c.droppedResults); dropped > 0 {
+		log.Println("WARNING:", dropped, "results dropped from the results channel, consumed too slowly")
+	}
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *client) closeOutputFiles() {
+	var closed *outputFiles
+	for _, s := range c.sessions {
+		if s != nil && s.out != closed {
+			s.out.Close()
+			closed = s.out
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package prober
+
+import (
+	"fmt"
+	"github.com/richiMarchi/latency-tester/server/serialization/protobuf"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Models of the processing time emulated by the server
+const (
+	FixedDelay     = "fixed"
+	UniformDelay   = "uniform"
+	LognormalDelay = "lognormal"
+	BusyDelay      = "busy"
+)
+
+// Parse a processing delay expressed as fixed:MS, uniform:MIN/MAX, lognormal:MEDIAN/SIGMA or busy:ITERATIONS
+func parseProcessingDelay(value string) (*protobuf.ProcessingDelay, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid processing delay %q, expected model:parameters", value)
+	}
+	params := strings.Split(parts[1], "/")
+	numbers := make([]float64, len(params))
+	for i, param := range params {
+		n, err := strconv.ParseFloat(param, 64)
+		if err != nil || n < 0 || math.IsNaN(n) || math.IsInf(n, 0) {
+			return nil, fmt.Errorf("invalid parameter %q of the processing delay", param)
+		}
+		numbers[i] = n
+	}
+	switch parts[0] {
+	case FixedDelay:
+		if len(numbers) == 1 {
+			return &protobuf.ProcessingDelay{Model: protobuf.DelayModel_FIXED, Milliseconds: numbers[0]}, nil
+		}
+	case UniformDelay:
+		if len(numbers) == 2 && numbers[0] <= numbers[1] {
+			return &protobuf.ProcessingDelay{
+				Model:           protobuf.DelayModel_UNIFORM,
+				Milliseconds:    numbers[0],
+				MaxMilliseconds: numbers[1],
+			}, nil
+		}
+	case LognormalDelay:
+		if len(numbers) == 2 {
+			return &protobuf.ProcessingDelay{
+				Model:        protobuf.DelayModel_LOGNORMAL,
+				Milliseconds: numbers[0],
+				Sigma:        numbers[1],
+			}, nil
+		}
+	case BusyDelay:
+		if iterations, err := strconv.ParseInt(parts[1], 10, 64); err == nil {
+			return &protobuf.ProcessingDelay{Model: protobuf.DelayModel_BUSY, Iterations: iterations}, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown processing delay model %q, allowed ones are fixed, uniform, lognormal and busy",
+			parts[0])
+	}
+	return nil, fmt.Errorf("invalid parameters of the %s processing delay %q", parts[0], parts[1])
+}
+
+// Format the processing delay applied by the server as the value of the processing-delay column
+func processingColumn(delay time.Duration) string {
+	return strconv.FormatFloat(float64(delay.Nanoseconds())/float64(time.Millisecond.Nanoseconds()), 'f', -1, 64)
+}
@@ -0,0 +1,194 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/gorilla/websocket"
+	"github.com/richiMarchi/latency-tester/server/serialization/protobuf"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Send the messages of all the streams of the session, then close its connection
+func (s *session) run(ctx context.Context) error {
+	var streams sync.WaitGroup
+	errs := make(chan error, s.client.config.Streams)
+	for i := 1; i <= s.client.config.Streams; i++ {
+		streams.Add(1)
+		go func(streamId int32) {
+			defer streams.Done()
+			// The TCP statistics refer to the messages of the first stream
+			msgId := &s.msgId
+			if streamId != 1 {
+				msgId = new(int32)
+			}
+			errs <- s.requestSender(ctx, streamId, msgId)
+		}(int32(i))
+	}
+	streams.Wait()
+	close(errs)
+	var sendErr error
+	for err := range errs {
+		if err != nil && sendErr == nil {
+			sendErr = err
+		}
+	}
+	if sendErr != nil {
+		close(s.stop)
+		return sendErr
+	}
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	err := s.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	if err != nil {
+		log.Println("write close: ", err)
+	}
+	// The reader may be waiting for the reset of a connection dropped by the server after the last message
+	close(s.stop)
+	return nil
+}
+
+// Signal the new connection of the session to the reader, and to the TCP statistics goroutine if requested
+func (s *session) signalReset() {
+	s.reset <- s.conn
+	if s.client.config.TcpStats {
+		s.statsReset <- s.conn
+	}
+}
+
+// Send the message on the connection of the session, resetting the connection if the write fails
+func (s *session) send(jsonMap *protobuf.DataJSON) error {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	marshal, _ := proto.Marshal(jsonMap)
+	if tc := s.client.timestampingConnOf(s.conn); tc != nil {
+		tc.startMessage(jsonMap.StreamId, jsonMap.Id)
+		defer tc.endMessage()
+	}
+	err := s.conn.WriteMessage(websocket.TextMessage, marshal)
+	if err != nil && s.goingAway == s.conn {
+		// Planned reconnection: the message is sent on the new connection instead of the reset one
+		log.Println("Server going away, reconnecting...")
+		conn, connErr := s.client.reconnect(s.sourcePort())
+		if connErr != nil {
+			return connErr
+		}
+		s.conn = conn
+		s.signalReset()
+		err = s.conn.WriteMessage(websocket.TextMessage, marshal)
+	}
+	for err != nil {
+		log.Printf("Trying to reset connection...")
+		conn, connErr := s.client.connect(s.sourcePort())
+		if connErr != nil {
+			return connErr
+		}
+		s.conn = conn
+		s.signalReset()
+		jsonMap.Id = 0
+		jsonMap.Payload = []byte{}
+		resetMarshal, _ := proto.Marshal(jsonMap)
+		err = s.conn.WriteMessage(websocket.TextMessage, resetMarshal)
+	}
+	return nil
+}
+
+func (s *session) requestSender(ctx context.Context, streamId int32, msgId *int32) error {
+	c := s.client
+	c.lockThread()
+	// Create a random payload to avoid compression, unless requested
+	payloadSize := c.config.RequestBytes
+	if c.traceEntries != nil {
+		payloadSize = c.maxTraceRequestSize()
+	}
+	size, sized := s.streamSizeOf(streamId)
+	if sized {
+		payloadSize = size.request
+	}
+	payload := newPayload(payloadSize, c.pattern)
+	// If Reps == 0 then loop infinitely, otherwise loop Reps times
+	lastId := int32(0)
+	if c.config.Reps != 0 {
+		lastId = int32(c.config.Reps) + 1
+	}
+	for id := int32(1); id != lastId; id++ {
+		// Published for the TCP statistics goroutine
+		atomic.StoreInt32(msgId, id)
+		// Wake up as a new client on a fresh connection
+		if c.coldConnectionEnd(id - 1) {
+			conn, setup, err := c.connectWithSetup(s.sourcePort())
+			if err != nil {
+				return err
+			}
+			s.conn = conn
+			s.coldConnections.Store(id, setup)
+			s.signalReset()
+		}
+		// Create the message with message ID and the current timestamp, serialize with protobuf and send it
+		tmp := getTimestamp()
+		jsonMap := &protobuf.DataJSON{
+			Id:              id,
+			Payload:         payload,
+			ClientTimestamp: timestamppb.New(tmp),
+			ServerTimestamp: &timestamp.Timestamp{},
+			StreamId:        streamId,
+		}
+		if c.traceEntries != nil {
+			entry := c.traceEntryOf(id)
+			jsonMap.Payload = payload[:entry.requestSize]
+			jsonMap.ResponseSize = proto.Int32(int32(entry.responseSize))
+		}
+		if sized {
+			jsonMap.ResponseSize = proto.Int32(int32(size.response))
+		}
+		if err := s.send(jsonMap); err != nil {
+			return err
+		}
+		if s.faults != nil {
+			// The message is replaced by the reset one if the connection has been reset
+			s.faults.sent(streamId, id, jsonMap.Id == 0)
+		}
+		// Close the connection once the response is received, as a client going back to sleep
+		if c.coldConnectionEnd(id) && id+1 != lastId {
+			if !s.waitColdAck(id) {
+				log.Println("WARNING: response to message", id, "not received before closing the connection")
+			}
+			s.writeMutex.Lock()
+			s.closedConn = s.conn
+			s.writeMutex.Unlock()
+			_ = s.conn.WriteMessage(websocket.CloseMessage,
+				websocket.FormatCloseMessage(ReconnectingCloseCode, "reconnecting"))
+			s.conn.Close()
+		}
+		wait := time.Duration(c.config.Interval) * time.Millisecond
+		if offset, scheduled := c.scheduledOffset(id + 1); scheduled {
+			wait = c.sendStart.Add(offset).Sub(tmp)
+		}
+		if gap, present := c.idleGapAfter(id); present {
+			wait = gap
+			s.idleGapMessages.Store(id+1, gap)
+		}
+		tsDiff := wait - time.Duration(getTimestamp().Sub(tmp).Nanoseconds())
+		if tsDiff < 0 {
+			tsDiff = 0
+			// Messages sent back to back are never late
+			next, scheduled := c.scheduledOffset(id + 1)
+			current, _ := c.scheduledOffset(id)
+			if c.config.Verbose && (!scheduled || next != current) {
+				fmt.Println("WARNING: It was not possible to send message", id+1, "after the desired interval!")
+			}
+		}
+		select {
+		case <-ctx.Done():
+			log.Println("interrupt")
+			return nil
+		case <-time.After(tsDiff):
+		}
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package prober
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/richiMarchi/latency-tester/enhanced-client/client/sink"
+	"github.com/richiMarchi/latency-tester/server/serialization/protobuf"
+	"google.golang.org/protobuf/proto"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Replace the csv separators in the instance ID of the server
+var instanceReplacer = strings.NewReplacer(",", "_", "\n", "_", "\r", "_")
+
+func (s *session) readDispatcher() {
+	s.client.lockThread()
+	c := s.conn
+	s.watchGoingAway(c)
+	for {
+		// Read all incoming messages
+		_, message, err := c.ReadMessage()
+		if err != nil {
+			if strings.Contains(err.Error(), "1000") {
+				if s.client.config.Verbose {
+					fmt.Println("read: ", err)
+				}
+				close(s.done)
+				return
+			} else {
+				if s.serverGoingAway(c) {
+					log.Println("Reader thread: server going away, waiting for the planned reconnection...")
+				} else {
+					// The connections closed by the client to reconnect are not reset by the server
+					if s.faults != nil && !websocket.IsCloseError(err, ReconnectingCloseCode) &&
+						!strings.Contains(err.Error(), "use of closed network connection") {
+						s.recordFault(0, 0, ResetFault)
+					}
+					log.Println("Reader thread: waiting for connection to reset...")
+				}
+				select {
+				case c = <-s.reset:
+				case <-s.stop:
+					close(s.done)
+					return
+				}
+				s.watchGoingAway(c)
+				log.Println("Reader thread: connection reset signaled")
+				continue
+			}
+		}
+
+		s.handleMessage(c, &message)
+	}
+}
+
+// Mark the connection when the server closes it with the going away code (e.g. when it shuts down), so that the
+// sender reconnects as planned instead of resetting the connection. The mark is set by the close handler, hence before
+// the close reply is sent and the writes on the connection start failing
+func (s *session) watchGoingAway(c *websocket.Conn) {
+	reply := c.CloseHandler()
+	c.SetCloseHandler(func(code int, text string) error {
+		if code == websocket.CloseGoingAway {
+			s.writeMutex.Lock()
+			if c != s.closedConn {
+				s.goingAway = c
+			}
+			s.writeMutex.Unlock()
+		}
+		return reply(code, text)
+	})
+}
+
+// True if the server closed the connection going away
+func (s *session) serverGoingAway(c *websocket.Conn) bool {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	return s.goingAway == c
+}
+
+// Print the result on the standard output as a JSON line, with the same units of the csv files
+func printJsonLine(result Result) {
+	line, _ := json.Marshal(struct {
+		Connection      int     `json:"connection"`
+		Stream          int32   `json:"stream"`
+		Id              int32   `json:"id"`
+		ClientTimestamp int64   `json:"client_send_timestamp"`
+		ServerTimestamp int64   `json:"server_timestamp"`
+		Rtt             float64 `json:"e2e_rtt"`
+		Phase           string  `json:"phase"`
+		ProcessingDelay float64 `json:"processing_delay,omitempty"`
+		ServerInstance  string  `json:"server_instance,omitempty"`
+	}{
+		Connection:      result.Connection,
+		Stream:          result.Stream,
+		Id:              result.Id,
+		ClientTimestamp: result.ClientTimestamp.UnixNano(),
+		ServerTimestamp: result.ServerTimestamp.UnixNano(),
+		Rtt:             float64(result.Rtt.Nanoseconds()) / float64(time.Millisecond.Nanoseconds()),
+		Phase:           result.Phase,
+		ProcessingDelay: float64(result.ProcessingDelay.Nanoseconds()) / float64(time.Millisecond.Nanoseconds()),
+		ServerInstance:  result.ServerInstance,
+	})
+	os.Stdout.Write(append(line, '\n'))
+}
+
+// Deserialize the message received on the connection and store data in the files of the session
+func (s *session) handleMessage(conn *websocket.Conn, message *[]byte) {
+	received := getTimestamp()
+	c := s.client
+	jsonMap := &protobuf.DataJSON{}
+	if err := proto.Unmarshal(*message, jsonMap); err != nil {
+		log.Println("invalid response: ", err)
+		if s.faults != nil {
+			s.recordFault(0, 0, InvalidFault)
+		}
+		return
+	}
+	if s.faults != nil && jsonMap.Id != 0 {
+		fault := s.faults.received(jsonMap.StreamId, jsonMap.Id)
+		if fault != "" {
+			s.recordFault(jsonMap.StreamId, jsonMap.Id, fault)
+		}
+		// The rtt of the duplicate responses is not stored twice
+		if fault == DuplicateFault {
+			return
+		}
+		if corruptedPayload(jsonMap) {
+			s.recordFault(jsonMap.StreamId, jsonMap.Id, CorruptedFault)
+		}
+	}
+	result := Result{
+		Connection:               s.id,
+		Stream:                   jsonMap.StreamId,
+		Id:                       jsonMap.Id,
+		ClientTimestamp:          jsonMap.ClientTimestamp.AsTime(),
+		ServerTimestamp:          jsonMap.ServerTimestamp.AsTime(),
+		Rtt:                      -1,
+		Phase:                    c.messagePhase(jsonMap.Id, jsonMap.ClientTimestamp.AsTime()),
+		ServerReceiveTimestamp:   serverTime(jsonMap.ServerReceiveTimestamp),
+		ProcessingStartTimestamp: serverTime(jsonMap.ProcessingStartTimestamp),
+		ProcessingEndTimestamp:   serverTime(jsonMap.ProcessingEndTimestamp),
+		ServerSendTimestamp:      serverTime(jsonMap.ServerSendTimestamp),
+		ReceiveTimestamp:         received,
+		ServerInstance:           jsonMap.Instance,
+	}
+	columns := c.optionalColumns(jsonMap.Id, jsonMap.StreamId, jsonMap.ClientTimestamp.AsTime())
+	if tc := c.timestampingConnOf(conn); tc != nil && jsonMap.Id != 0 {
+		result.KernelTxTimestamp, _ = tc.takeTxTimestamp(jsonMap.StreamId, jsonMap.Id)
+		result.KernelRxTimestamp, _ = tc.rxTimestamp()
+	}
+	if c.config.KernelTimestamps != "" {
+		columns = append(columns, kernelColumns(result.KernelTxTimestamp, result.KernelRxTimestamp)...)
+	}
+	if c.processingDelay != nil {
+		if jsonMap.ProcessingDelay != nil {
+			result.ProcessingDelay = jsonMap.ProcessingDelay.AsDuration()
+			columns = append(columns, processingColumn(result.ProcessingDelay))
+		} else {
+			columns = append(columns, "")
+		}
+	}
+	if c.config.ServerTimestamps {
+		columns = append(columns, serverColumns(jsonMap, received)...)
+	}
+	if c.config.ServerInstance {
+		// The instance ID is configured on the server, it must not break the csv
+		columns = append(columns, instanceReplacer.Replace(jsonMap.Instance))
+	}
+	if size, sized := s.streamSizeOf(jsonMap.StreamId); sized {
+		columns = append(columns, strconv.FormatUint(size.request, 10), strconv.FormatUint(size.response, 10))
+	}
+	if c.mergedOutput() {
+		columns = append(columns, strconv.Itoa(s.id))
+	}
+	record := sink.Record{
+		ClientTimestamp: jsonMap.ClientTimestamp.AsTime(),
+		ServerTimestamp: jsonMap.ServerTimestamp.AsTime(),
+		Rtt:             -1,
+		Columns:         columns,
+	}
+	s.out.Lock()
+	if jsonMap.Id == 0 {
+		log.Println("Connection Reset")
+		if err := s.out.rtt.Write(record); err != nil {
+			log.Println("write result: ", err)
+		}
+	} else {
+		latency := received.Sub(jsonMap.ClientTimestamp.AsTime())
+		result.Rtt = latency
+		if c.config.JsonLines {
+			printJsonLine(result)
+		} else if c.config.Verbose && (c.config.Connections > 1 || c.config.Streams > 1) {
+			fmt.Printf("%d-%d-%d.\t%f ms\n", s.id, jsonMap.StreamId, jsonMap.Id,
+				float64(latency.Nanoseconds())/float64(time.Millisecond.Nanoseconds()))
+		} else if c.config.Verbose {
+			fmt.Printf("%d.\t%f ms\n", jsonMap.Id, float64(latency.Nanoseconds())/float64(time.Millisecond.Nanoseconds()))
+		}
+		record.Rtt = float64(latency.Nanoseconds()) / float64(time.Millisecond.Nanoseconds())
+		if err := s.out.rtt.Write(record); err != nil {
+			log.Println("write result: ", err)
+		}
+		// The first message after an idle gap is stored in the idle-gap file too
+		if gap, present := s.idleGapMessages.Load(jsonMap.Id); present {
+			idleRtt := s.out.idleGaps
+			idleRtt.WriteString(strconv.FormatInt(gap.(time.Duration).Milliseconds(), 10))
+			idleRtt.WriteString(",")
+			idleRtt.WriteString(strconv.FormatInt(jsonMap.ClientTimestamp.AsTime().UnixNano(), 10))
+			idleRtt.WriteString(",")
+			idleRtt.WriteString(strconv.FormatFloat(
+				float64(latency.Nanoseconds())/float64(time.Millisecond.Nanoseconds()), 'f', -1, 64))
+			idleRtt.WriteString(s.connectionColumn())
+			idleRtt.WriteString("\n")
+		}
+		// Each hop of the message is stored on its own line
+		if c.config.Hops {
+			result.Hops = messageHops(jsonMap.Hops, latency)
+			for i, hop := range result.Hops {
+				hopsFile := s.out.hops
+				hopsFile.WriteString(strconv.FormatInt(jsonMap.ClientTimestamp.AsTime().UnixNano(), 10))
+				hopsFile.WriteString(",")
+				hopsFile.WriteString(strconv.Itoa(i + 1))
+				hopsFile.WriteString(",")
+				hopsFile.WriteString(instanceReplacer.Replace(hop.Instance))
+				hopsFile.WriteString(",")
+				hopsFile.WriteString(hopColumn(hop.LinkRtt))
+				hopsFile.WriteString(",")
+				hopsFile.WriteString(hopColumn(hop.Residence))
+				hopsFile.WriteString(s.connectionColumn())
+				hopsFile.WriteString("\n")
+			}
+		}
+		// The first message of a cold connection is stored with the setup times of the connection
+		if setup, present := s.coldConnections.Load(jsonMap.Id); present {
+			coldRtt := s.out.coldConnections
+			coldRtt.WriteString(strconv.FormatInt(setup.(connectionSetup).timestamp.UnixNano(), 10))
+			coldRtt.WriteString(",")
+			coldRtt.WriteString(strconv.FormatFloat(
+				float64(setup.(connectionSetup).tcp.Nanoseconds())/float64(time.Millisecond.Nanoseconds()), 'f', -1, 64))
+			coldRtt.WriteString(",")
+			coldRtt.WriteString(strconv.FormatFloat(
+				float64(setup.(connectionSetup).total.Nanoseconds())/float64(time.Millisecond.Nanoseconds()), 'f', -1, 64))
+			coldRtt.WriteString(",")
+			coldRtt.WriteString(strconv.FormatFloat(
+				float64(latency.Nanoseconds())/float64(time.Millisecond.Nanoseconds()), 'f', -1, 64))
+			coldRtt.WriteString(s.connectionColumn())
+			coldRtt.WriteString("\n")
+		}
+		if c.coldConnectionEnd(jsonMap.Id) {
+			select {
+			case s.coldAcks <- jsonMap.Id:
+			default:
+			}
+		}
+	}
+	s.out.Unlock()
+	// The result is published after releasing the files, and dropped if the consumer is late, so that the reader is
+	// never blocked and the receive timestamps of the next messages are not delayed
+	if c.results != nil {
+		select {
+		case c.results <- result:
+		default:
+			atomic.AddUint64(&c.droppedResults, 1)
+		}
+	}
+}
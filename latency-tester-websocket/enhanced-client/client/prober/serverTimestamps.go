@@ -0,0 +1,43 @@
+package prober
+
+import (
+	"github.com/richiMarchi/latency-tester/server/serialization/protobuf"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"strconv"
+	"time"
+)
+
+// Columns of the rtt decomposition, in the order of serverColumns
+var serverTimestampsHeader = []string{"server-receive-timestamp", "server-send-timestamp", "uplink", "server-residence",
+	"downlink"}
+
+// Return the time of the timestamp reported by the server, zero if it has not been reported
+func serverTime(timestamp *timestamppb.Timestamp) time.Time {
+	if timestamp == nil {
+		return time.Time{}
+	}
+	return timestamp.AsTime()
+}
+
+// Return the columns decomposing the rtt of the message into uplink, server residence and downlink, in milliseconds.
+// The residence is measured on the server clock only, while the uplink and the downlink are meaningful only if the
+// clocks of the client and of the server are synchronised. The columns are empty if the server does not report its
+// timestamps
+func serverColumns(jsonMap *protobuf.DataJSON, received time.Time) []string {
+	columns := []string{"", "", "", "", ""}
+	if jsonMap.ServerReceiveTimestamp == nil || jsonMap.ServerSendTimestamp == nil || jsonMap.Id == 0 {
+		return columns
+	}
+	serverReceive := jsonMap.ServerReceiveTimestamp.AsTime()
+	serverSend := jsonMap.ServerSendTimestamp.AsTime()
+	columns[0] = strconv.FormatInt(serverReceive.UnixNano(), 10)
+	columns[1] = strconv.FormatInt(serverSend.UnixNano(), 10)
+	columns[2] = milliseconds(serverReceive.Sub(jsonMap.ClientTimestamp.AsTime()))
+	columns[3] = milliseconds(serverSend.Sub(serverReceive))
+	columns[4] = milliseconds(received.Sub(serverSend))
+	return columns
+}
+
+func milliseconds(d time.Duration) string {
+	return strconv.FormatFloat(float64(d.Nanoseconds())/float64(time.Millisecond.Nanoseconds()), 'f', -1, 64)
+}
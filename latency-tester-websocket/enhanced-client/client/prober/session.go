@@ -0,0 +1,195 @@
+package prober
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/richiMarchi/latency-tester/enhanced-client/client/sink"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Files the results are stored in, shared by all the sessions when the output is merged
+type outputFiles struct {
+	sync.Mutex
+	rtt             sink.ResultSink
+	idleGaps        *os.File
+	coldConnections *os.File
+	hops            *os.File
+	faults          *os.File
+}
+
+// WebSocket connection towards the server, with its own sender and reader goroutines
+type session struct {
+	client *client
+	id     int
+	conn   *websocket.Conn
+	msgId  int32
+	out    *outputFiles
+	// The streams of the session write on the same connection one at a time
+	writeMutex sync.Mutex
+	// Connection closed by the client to reconnect and connection the server announced it is going away on, both
+	// guarded by writeMutex
+	closedConn *websocket.Conn
+	goingAway  *websocket.Conn
+	// Messages sent right after an idle gap, with the duration of the gap that preceded them
+	idleGapMessages sync.Map
+	// First messages sent on each cold connection, with the setup of the connection
+	coldConnections sync.Map
+	// Faults detected on the messages, if requested
+	faults *faultDetector
+	// IDs of the last messages of the cold connections whose response has been received
+	coldAcks chan int32
+	// New connections of the session, for the reader and for the TCP statistics goroutine if requested
+	reset      chan *websocket.Conn
+	statsReset chan *websocket.Conn
+	// Closed if the session cannot go on, so that the reader stops waiting for the connection to be reset
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newSession(c *client, id int, out *outputFiles) *session {
+	s := &session{
+		client:     c,
+		id:         id,
+		out:        out,
+		coldAcks:   make(chan int32, 1),
+		reset:      make(chan *websocket.Conn, 2),
+		statsReset: make(chan *websocket.Conn, 2),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	if c.config.DetectFaults {
+		s.faults = newFaultDetector()
+	}
+	return s
+}
+
+// Create the files requested by the configuration, whose names start with the given prefix
+func (c *client) createOutputFiles(prefix string) (*outputFiles, error) {
+	out := &outputFiles{}
+	var fileErr error
+	extension, _ := sink.Extension(c.config.Format)
+	columns := c.optionalHeader()
+	if c.mergedOutput() {
+		columns = append(columns, "connection")
+	}
+	if c.rotation.Enabled() {
+		out.rtt, fileErr = sink.CreateRotating(c.config.Format, prefix, columns, c.rotation)
+	} else {
+		out.rtt, fileErr = sink.Create(c.config.Format, prefix+extension, columns)
+	}
+	if fileErr != nil {
+		return nil, fileErr
+	}
+
+	if c.config.ReconnectEvery != 0 {
+		out.coldConnections, fileErr = os.Create(prefix + "_cold-connections.csv")
+		if fileErr != nil {
+			out.Close()
+			return nil, fileErr
+		}
+		out.coldConnections.WriteString("#connection-timestamp,tcp-setup,total-setup,first-msg-rtt" +
+			c.connectionHeader() + "\n")
+	}
+
+	if len(c.idleGapList) > 0 {
+		out.idleGaps, fileErr = os.Create(prefix + "_idle-gaps.csv")
+		if fileErr != nil {
+			out.Close()
+			return nil, fileErr
+		}
+		out.idleGaps.WriteString("#idle-gap,client-send-timestamp,e2e-rtt" + c.connectionHeader() + "\n")
+	}
+
+	if c.config.DetectFaults {
+		out.faults, fileErr = os.Create(prefix + "_faults.csv")
+		if fileErr != nil {
+			out.Close()
+			return nil, fileErr
+		}
+		out.faults.WriteString("#timestamp,stream,id,fault" + c.connectionHeader() + "\n")
+	}
+
+	if c.config.Hops {
+		out.hops, fileErr = os.Create(prefix + "_hops.csv")
+		if fileErr != nil {
+			out.Close()
+			return nil, fileErr
+		}
+		out.hops.WriteString("#client-send-timestamp,hop,instance,link-rtt,residence" + c.connectionHeader() + "\n")
+	}
+	return out, nil
+}
+
+func (out *outputFiles) Close() {
+	// The buffered results are flushed on close, so that a failed write is reported at the end
+	if err := out.rtt.Close(); err != nil {
+		log.Println("close results: ", err)
+	}
+	if out.coldConnections != nil {
+		out.coldConnections.Close()
+	}
+	if out.idleGaps != nil {
+		out.idleGaps.Close()
+	}
+	if out.hops != nil {
+		out.hops.Close()
+	}
+	if out.faults != nil {
+		out.faults.Close()
+	}
+}
+
+// True if the sessions store their results in the same files, with the connection column
+func (c *client) mergedOutput() bool {
+	return c.config.Connections > 1 && !c.config.SplitConnections
+}
+
+// Return the header of the connection column, if the output of the sessions is merged
+func (c *client) connectionHeader() string {
+	if c.mergedOutput() {
+		return ",connection"
+	}
+	return ""
+}
+
+// Return the connection column of the session, if the output of the sessions is merged
+func (s *session) connectionColumn() string {
+	if s.client.mergedOutput() {
+		return "," + strconv.Itoa(s.id)
+	}
+	return ""
+}
+
+// Prefix of the files of the session with the given id
+func (c *client) sessionPrefix(id int) string {
+	if c.config.Connections > 1 && c.config.SplitConnections {
+		return c.config.LogFile + "-c" + strconv.Itoa(id)
+	}
+	return c.config.LogFile
+}
+
+// Source port of the session, consecutive ones starting from the requested one
+func (s *session) sourcePort() int {
+	if s.client.config.SrcPort == 0 {
+		return 0
+	}
+	return s.client.config.SrcPort + s.id - 1
+}
+
+// Wait until the response to the message is received, false if it does not arrive in time
+func (s *session) waitColdAck(id int32) bool {
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case acked := <-s.coldAcks:
+			if acked == id {
+				return true
+			}
+		case <-timeout:
+			return false
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package prober
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Request and response payload sizes of a stream, in bytes
+type streamSize struct {
+	request  uint64
+	response uint64
+}
+
+// Parse a comma separated list of <request-bytes>/<response-bytes> stream sizes
+func parseStreamSizes(value string) ([]streamSize, error) {
+	var sizes []streamSize
+	if value == "" {
+		return sizes, nil
+	}
+	for _, item := range strings.Split(value, ",") {
+		parts := strings.Split(strings.TrimSpace(item), "/")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid size %q, expected <request-bytes>/<response-bytes>", item)
+		}
+		request, requestErr := strconv.ParseUint(parts[0], 10, 31)
+		response, responseErr := strconv.ParseUint(parts[1], 10, 31)
+		if requestErr != nil || responseErr != nil {
+			return nil, fmt.Errorf("invalid size %q", item)
+		}
+		sizes = append(sizes, streamSize{request: request, response: response})
+	}
+	return sizes, nil
+}
+
+// Return the sizes of the stream of the session, assigned in turn to the streams of every connection, so that with a
+// single stream for each connection they are assigned to the connections. False if the sizes are not set
+func (s *session) streamSizeOf(streamId int32) (streamSize, bool) {
+	sizes := s.client.streamSizes
+	if len(sizes) == 0 {
+		return streamSize{}, false
+	}
+	index := (s.id-1)*s.client.config.Streams + int(streamId) - 1
+	return sizes[index%len(sizes)], true
+}
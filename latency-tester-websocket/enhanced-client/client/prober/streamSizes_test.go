@@ -0,0 +1,37 @@
+package prober
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseStreamSizes(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []streamSize
+		err   bool
+	}{
+		{"", nil, false},
+		{"64/1024", []streamSize{{request: 64, response: 1024}}, false},
+		{"64/1024,1000000/0", []streamSize{{request: 64, response: 1024}, {request: 1000000, response: 0}}, false},
+		{"64/1024, 16/16", []streamSize{{request: 64, response: 1024}, {request: 16, response: 16}}, false},
+		{"64", nil, true},
+		{"64/1024/1", nil, true},
+		{"64/", nil, true},
+		{"-64/1024", nil, true},
+		{"64/big", nil, true},
+		{"2147483648/1024", nil, true},
+		{"64/1024,", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseStreamSizes(tt.value)
+			if (err != nil) != tt.err {
+				t.Fatalf("error = %v, want error %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sizes = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
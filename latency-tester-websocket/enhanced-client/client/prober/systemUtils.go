@@ -1,16 +1,16 @@
-package main
+package prober
 
 import (
 	"fmt"
 	"github.com/brucespang/go-tcpinfo"
 	"github.com/google/go-cmp/cmp"
-	"github.com/gorilla/websocket"
 	"log"
 	"os"
 	"os/exec"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 func customTraceroute(
@@ -20,30 +20,35 @@ func customTraceroute(
 	outputFile.WriteString(string(output))
 }
 
-func getSocketStats(
-	conn *websocket.Conn,
-	ssReading *bool,
+func (c *client) getSocketStats(
+	s *session,
 	outputFile *os.File,
-	wg *sync.WaitGroup,
-	msgId *int32,
-	reset chan *websocket.Conn) {
+	wg *sync.WaitGroup) {
 	defer wg.Done()
 	defer outputFile.Close()
 
-	tcpConn := getTCPConnFromWebsocketConn(conn)
+	conn := s.conn
+	tcpConn := c.getTCPConnFromWebsocketConn(conn)
 	var sockOpt []TimedTCPInfo
-	for *ssReading {
-		// Check if the connection changed
+reading:
+	for {
+		// Check if the reading has been stopped or the connection changed
 		select {
-		case conn = <-reset:
-			tcpConn = getTCPConnFromWebsocketConn(conn)
+		case <-c.ssStop:
+			break reading
+		case conn = <-s.statsReset:
+			tcpConn = c.getTCPConnFromWebsocketConn(conn)
 			outputFile.WriteString(strconv.FormatInt(getTimestamp().UnixNano(), 10) + ",-1,Connection Reset\n")
 		default:
 		}
-		if *msgId != 0 {
-			tcpInfo, _ := tcpinfo.GetsockoptTCPInfo(tcpConn)
+		if msgId := atomic.LoadInt32(&s.msgId); msgId != 0 {
+			tcpInfo, err := tcpinfo.GetsockoptTCPInfo(tcpConn)
+			// The connection could have been closed in the meantime
+			if err != nil {
+				continue
+			}
 			sockOpt = append(sockOpt, TimedTCPInfo{
-				MsgId:     *msgId,
+				MsgId:     msgId,
 				Timestamp: getTimestamp(),
 				TcpInfo:   tcpInfo,
 			})
@@ -0,0 +1,84 @@
+package prober
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Request of an application trace, with the offset from the start of the execution it has to be sent at
+type traceEntry struct {
+	line         int
+	offset       time.Duration
+	requestSize  uint64
+	responseSize uint64
+}
+
+// Load a trace file made of "<offset-ms>,<request-bytes>,<response-bytes>" lines, skipping empty and '#' ones
+func loadTrace(filename string) ([]traceEntry, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []traceEntry
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, ",")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expected <offset-ms>,<request-bytes>,<response-bytes>", line)
+		}
+		offset, offsetErr := strconv.ParseFloat(strings.TrimSpace(fields[0]), 64)
+		requestSize, requestErr := strconv.ParseUint(strings.TrimSpace(fields[1]), 10, 31)
+		responseSize, responseErr := strconv.ParseUint(strings.TrimSpace(fields[2]), 10, 31)
+		if offsetErr != nil || requestErr != nil || responseErr != nil || offset < 0 || math.IsNaN(offset) ||
+			math.IsInf(offset, 0) {
+			return nil, fmt.Errorf("line %d: invalid values", line)
+		}
+		entry := traceEntry{
+			line:         line,
+			offset:       time.Duration(offset * float64(time.Millisecond)),
+			requestSize:  requestSize,
+			responseSize: responseSize,
+		}
+		if len(entries) > 0 && entry.offset < entries[len(entries)-1].offset {
+			return nil, fmt.Errorf("line %d: offsets must not decrease", line)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no requests in the trace")
+	}
+	return entries, nil
+}
+
+// Return the trace entry replayed by the message, clamping the ids after the last one
+func (c *client) traceEntryOf(id int32) traceEntry {
+	if int(id) > len(c.traceEntries) {
+		return c.traceEntries[len(c.traceEntries)-1]
+	}
+	return c.traceEntries[id-1]
+}
+
+// Return the biggest request payload of the trace
+func (c *client) maxTraceRequestSize() uint64 {
+	var max uint64
+	for _, entry := range c.traceEntries {
+		if entry.requestSize > max {
+			max = entry.requestSize
+		}
+	}
+	return max
+}
@@ -0,0 +1,63 @@
+package prober
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLoadTrace(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name    string
+		content string
+		want    []traceEntry
+		err     bool
+	}{
+		{"single request", "0,100,200\n", []traceEntry{{line: 1, offset: 0, requestSize: 100, responseSize: 200}},
+			false},
+		{"comments, blanks and spaces", "# offset,request,response\n\n 0 , 10, 20\n  \n12.5,30,40\n",
+			[]traceEntry{{line: 3, offset: 0, requestSize: 10, responseSize: 20},
+				{line: 5, offset: 12500 * time.Microsecond, requestSize: 30, responseSize: 40}}, false},
+		{"equal offsets", "5,1,1\n5,2,2", []traceEntry{{line: 1, offset: 5 * ms, requestSize: 1, responseSize: 1},
+			{line: 2, offset: 5 * ms, requestSize: 2, responseSize: 2}}, false},
+		{"empty", "", nil, true},
+		{"only comments", "# nothing\n\n", nil, true},
+		{"missing field", "0,100\n", nil, true},
+		{"extra field", "0,100,200,300\n", nil, true},
+		{"negative offset", "-1,100,200\n", nil, true},
+		{"NaN offset", "NaN,100,200\n", nil, true},
+		{"infinite offset", "inf,100,200\n", nil, true},
+		{"negative size", "0,-100,200\n", nil, true},
+		{"size over 31 bits", "0,100,2147483648\n", nil, true},
+		{"not a number", "0,big,200\n", nil, true},
+		{"decreasing offsets", "10,1,1\n5,1,1\n", nil, true},
+	}
+	dir, err := ioutil.TempDir("", "trace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(dir, "trace"+strconv.Itoa(i)+".csv")
+			if err := ioutil.WriteFile(filename, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadTrace(filename)
+			if (err != nil) != tt.err {
+				t.Fatalf("error = %v, want error %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("entries = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if _, err := loadTrace(filepath.Join(dir, "missing.csv")); err == nil {
+		t.Error("missing trace loaded")
+	}
+}
@@ -0,0 +1,110 @@
+package prober
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	BurstProfile = "burst"
+	OnOffProfile = "onoff"
+	RampProfile  = "ramp"
+)
+
+// Traffic profile replacing the fixed send interval, defined as "<kind>:<param>/<param>/..." with values in ms:
+// - burst:N/T sends N messages back to back every T ms
+// - onoff:I/ON/OFF sends a message every I ms for ON ms, then stays silent for OFF ms
+// - ramp:FROM/TO linearly changes the send interval from FROM ms to TO ms throughout the execution
+type trafficProfile struct {
+	kind   string
+	params []uint64
+}
+
+func parseTrafficProfile(value string) (*trafficProfile, error) {
+	if value == "" {
+		return nil, nil
+	}
+	kind, params, err := ProfileParams(value)
+	if err != nil {
+		return nil, err
+	}
+	return &trafficProfile{kind: kind, params: params}, nil
+}
+
+// Split the traffic profile into its kind and its parameters, all of them positive. The enhanced client checks the
+// profiles of its settings with it too, so that they are the ones accepted by the client
+func ProfileParams(value string) (string, []uint64, error) {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return "", nil, fmt.Errorf("invalid profile %q, expected <kind>:<params>", value)
+	}
+	var params []uint64
+	for _, param := range strings.Split(parts[1], "/") {
+		parsed, err := strconv.ParseUint(param, 10, 64)
+		if err != nil || parsed == 0 {
+			return "", nil, fmt.Errorf("invalid parameter %q in profile %q, expected a positive integer", param, value)
+		}
+		params = append(params, parsed)
+	}
+	expected := map[string]int{BurstProfile: 2, OnOffProfile: 3, RampProfile: 2}
+	count, known := expected[parts[0]]
+	if !known {
+		return "", nil, fmt.Errorf("unknown profile %q, allowed ones are burst, onoff and ramp", parts[0])
+	}
+	if len(params) != count {
+		return "", nil, fmt.Errorf("profile %q requires %d parameters", parts[0], count)
+	}
+	return parts[0], params, nil
+}
+
+// Messages sent in each burst or on period
+func (p *trafficProfile) burstLength() uint64 {
+	switch p.kind {
+	case BurstProfile:
+		return p.params[0]
+	case OnOffProfile:
+		length := (p.params[1] + p.params[0] - 1) / p.params[0]
+		if length == 0 {
+			length = 1
+		}
+		return length
+	default:
+		return 0
+	}
+}
+
+// Offset from the start of the execution of the given repetitions at which the message has to be sent
+func (p *trafficProfile) offset(id int32, reps uint64) time.Duration {
+	index := uint64(id) - 1
+	switch p.kind {
+	case BurstProfile:
+		return time.Duration(index/p.params[0]*p.params[1]) * time.Millisecond
+	case OnOffProfile:
+		length := p.burstLength()
+		period := p.params[1] + p.params[2]
+		return time.Duration(index/length*period+index%length*p.params[0]) * time.Millisecond
+	case RampProfile:
+		// Sum of the first index intervals of the arithmetic progression from FROM to TO
+		gaps := float64(reps) - 1
+		if gaps < 1 {
+			gaps = 1
+		}
+		from := float64(p.params[0])
+		step := (float64(p.params[1]) - from) / gaps
+		n := float64(index)
+		return time.Duration((n*from + step*n*(n-1)/2) * float64(time.Millisecond))
+	default:
+		return 0
+	}
+}
+
+// Burst the message belongs to and its position inside it, false if the profile has no bursts
+func (p *trafficProfile) burstTag(id int32) (uint64, uint64, bool) {
+	length := p.burstLength()
+	if length == 0 || id == 0 {
+		return 0, 0, false
+	}
+	return (uint64(id) - 1) / length, (uint64(id) - 1) % length, true
+}
@@ -0,0 +1,108 @@
+package prober
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProfileParams(t *testing.T) {
+	tests := []struct {
+		value  string
+		kind   string
+		params []uint64
+		err    bool
+	}{
+		{"burst:5/100", BurstProfile, []uint64{5, 100}, false},
+		{"onoff:10/2000/3000", OnOffProfile, []uint64{10, 2000, 3000}, false},
+		{"ramp:100/10", RampProfile, []uint64{100, 10}, false},
+		{"burst:5/0", "", nil, true},
+		{"burst:0/100", "", nil, true},
+		{"onoff:10/0/3000", "", nil, true},
+		{"ramp:0/10", "", nil, true},
+		{"burst:5/-100", "", nil, true},
+		{"burst:5/fast", "", nil, true},
+		{"burst:5", "", nil, true},
+		{"onoff:10/2000", "", nil, true},
+		{"ramp:100/10/1", "", nil, true},
+		{"sine:100/10", "", nil, true},
+		{"100", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			kind, params, err := ProfileParams(tt.value)
+			if (err != nil) != tt.err {
+				t.Fatalf("error = %v, want error %v", err, tt.err)
+			}
+			if kind != tt.kind || !reflect.DeepEqual(params, tt.params) {
+				t.Errorf("profile = %s %v, want %s %v", kind, params, tt.kind, tt.params)
+			}
+		})
+	}
+}
+
+func TestTrafficProfileOffset(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		profile string
+		reps    uint64
+		id      int32
+		want    time.Duration
+	}{
+		{"burst:3/100", 10, 1, 0},
+		{"burst:3/100", 10, 3, 0},
+		{"burst:3/100", 10, 4, 100 * ms},
+		{"burst:3/100", 10, 7, 200 * ms},
+		{"onoff:10/30/70", 10, 1, 0},
+		{"onoff:10/30/70", 10, 3, 20 * ms},
+		{"onoff:10/30/70", 10, 4, 100 * ms},
+		{"onoff:10/30/70", 10, 5, 110 * ms},
+		{"onoff:10/30/70", 10, 7, 200 * ms},
+		{"onoff:10/5/95", 10, 2, 100 * ms},
+		{"ramp:100/10", 10, 1, 0},
+		{"ramp:100/10", 10, 2, 100 * ms},
+		{"ramp:100/10", 10, 3, 190 * ms},
+		{"ramp:100/10", 10, 10, 540 * ms},
+		{"ramp:10/100", 10, 10, 450 * ms},
+		{"ramp:100/10", 1, 2, 100 * ms},
+		{"ramp:100/10", 0, 3, 110 * ms},
+	}
+	for _, tt := range tests {
+		profile, err := parseTrafficProfile(tt.profile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := profile.offset(tt.id, tt.reps); got != tt.want {
+			t.Errorf("%s with %d reps: offset of %d = %v, want %v", tt.profile, tt.reps, tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestTrafficProfileBurstTag(t *testing.T) {
+	tests := []struct {
+		profile  string
+		id       int32
+		index    uint64
+		position uint64
+		tagged   bool
+	}{
+		{"burst:3/100", 1, 0, 0, true},
+		{"burst:3/100", 3, 0, 2, true},
+		{"burst:3/100", 4, 1, 0, true},
+		{"burst:3/100", 0, 0, 0, false},
+		{"onoff:10/30/70", 5, 1, 1, true},
+		{"onoff:10/5/95", 2, 1, 0, true},
+		{"ramp:100/10", 5, 0, 0, false},
+	}
+	for _, tt := range tests {
+		profile, err := parseTrafficProfile(tt.profile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		index, position, tagged := profile.burstTag(tt.id)
+		if index != tt.index || position != tt.position || tagged != tt.tagged {
+			t.Errorf("%s: tag of %d = %d/%d/%v, want %d/%d/%v", tt.profile, tt.id, index, position, tagged,
+				tt.index, tt.position, tt.tagged)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+package prober
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/brucespang/go-tcpinfo"
+	"github.com/gorilla/websocket"
+	"github.com/richiMarchi/latency-tester/server/serialization/protobuf"
+	"log"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+type TimedTCPInfo struct {
+	MsgId     int32
+	Timestamp time.Time
+	TcpInfo   *tcpinfo.TCPInfo
+}
+
+// Messages at the beginning or at the end of the execution that are recorded but flagged
+type exclusionWindow struct {
+	messages uint64
+	duration time.Duration
+}
+
+// Time spent to establish a connection, both for the TCP part and for the whole TCP+TLS+WebSocket setup
+type connectionSetup struct {
+	timestamp time.Time
+	tcp       time.Duration
+	total     time.Duration
+	// Identity of the server replied to the handshake
+	server *protobuf.ServerIdentity
+}
+
+const (
+	WarmupPhase   = "warmup"
+	SteadyPhase   = "steady"
+	CooldownPhase = "cooldown"
+)
+
+// Application close code of the connections closed by the client to reconnect (e.g. in cold connection mode), distinct
+// from the going away one of the servers shutting down
+const ReconnectingCloseCode = 4000
+
+// Attempts to reconnect after the server went away and the pause between them
+const (
+	ReconnectAttempts = 10
+	ReconnectBackoff  = time.Second
+)
+
+func (c *client) connect(localPort int) (*websocket.Conn, error) {
+	conn, _, err := c.connectWithSetup(localPort)
+	return conn, err
+}
+
+// Open a new websocket connection after the server went away, retrying while the replacement server (e.g. the new pod
+// of a rollout) is not reachable yet
+func (c *client) reconnect(localPort int) (*websocket.Conn, error) {
+	var err error
+	for attempt := 0; attempt < ReconnectAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(ReconnectBackoff)
+		}
+		var conn *websocket.Conn
+		if conn, err = c.connect(localPort); err == nil {
+			return conn, nil
+		}
+		log.Println("reconnect: ", err)
+	}
+	return nil, err
+}
+
+// Open the websocket connection, measuring the time spent for the TCP connection and for the whole setup
+func (c *client) connectWithSetup(localPort int) (*websocket.Conn, connectionSetup, error) {
+	addrParts := strings.Split(c.config.Address, "/")
+	pathString := ""
+	for _, part := range addrParts[1:] {
+		pathString += "/" + part
+	}
+	setup := connectionSetup{timestamp: getTimestamp()}
+	netDialer := &net.Dialer{}
+	if localPort != 0 {
+		netDialer.LocalAddr = &net.TCPAddr{Port: localPort}
+	}
+	dialer := websocket.Dialer{
+		HandshakeTimeout: 10 * time.Second,
+		NetDialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			start := getTimestamp()
+			conn, err := netDialer.DialContext(ctx, network, addr)
+			setup.tcp = getTimestamp().Sub(start)
+			if err != nil || c.config.KernelTimestamps == "" {
+				return conn, err
+			}
+			tc, err := enableTimestamping(conn.(*net.TCPConn), c.config.KernelTimestamps)
+			if err != nil {
+				conn.Close()
+				return nil, err
+			}
+			return tc, nil
+		},
+	}
+	var u url.URL
+	if c.config.Tls {
+		conf := &tls.Config{InsecureSkipVerify: true}
+		if c.config.TlsResumption {
+			conf.ClientSessionCache = c.tlsSessionCache
+		}
+		dialer.TLSClientConfig = conf
+		u = url.URL{Scheme: "wss", Host: addrParts[0], Path: pathString + "/echo"}
+	} else {
+		u = url.URL{Scheme: "ws", Host: addrParts[0], Path: "/echo"}
+	}
+	conn, _, err := dialer.Dial(u.String(), nil)
+	if err != nil {
+		return nil, setup, errors.New("dial: " + err.Error())
+	}
+	setup.total = getTimestamp().Sub(setup.timestamp)
+	reply, err := c.handshake(conn)
+	if err != nil {
+		conn.Close()
+		return nil, setup, err
+	}
+	setup.server = reply.Identity
+	return conn, setup, nil
+}
+
+// Parse a window expressed as a number of messages (e.g. 10) or as a duration (e.g. 5s)
+func parseExclusionWindow(value string) (exclusionWindow, error) {
+	if value == "" {
+		return exclusionWindow{}, nil
+	}
+	if messages, err := strconv.ParseUint(value, 10, 64); err == nil {
+		return exclusionWindow{messages: messages}, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return exclusionWindow{}, fmt.Errorf("invalid window %q, expected a number of messages or a duration", value)
+	}
+	return exclusionWindow{duration: duration}, nil
+}
+
+func (w exclusionWindow) isEmpty() bool {
+	return w.messages == 0 && w.duration == 0
+}
+
+// True if the phase column has to be added to the output
+func (c *client) flaggingEnabled() bool {
+	return !c.warmupWindow.isEmpty() || !c.cooldownWindow.isEmpty()
+}
+
+// Return the names of the optional columns enabled by the flags
+func (c *client) optionalHeader() []string {
+	var header []string
+	if c.flaggingEnabled() {
+		header = append(header, "phase")
+	}
+	if c.profile != nil && c.profile.burstLength() != 0 {
+		header = append(header, "burst-index", "burst-position")
+	}
+	if c.traceEntries != nil {
+		header = append(header, "trace-line")
+	}
+	if c.config.Streams > 1 {
+		header = append(header, "stream")
+	}
+	header = append(header, "id")
+	if c.config.KernelTimestamps != "" {
+		header = append(header, "kernel-tx-timestamp", "kernel-rx-timestamp", "kernel-rtt")
+	}
+	if c.processingDelay != nil {
+		header = append(header, "processing-delay")
+	}
+	if c.config.ServerTimestamps {
+		header = append(header, serverTimestampsHeader...)
+	}
+	if c.config.ServerInstance {
+		header = append(header, "server-instance")
+	}
+	if len(c.streamSizes) > 0 {
+		header = append(header, "request-size", "response-size")
+	}
+	return header
+}
+
+// Return the values of the optional columns enabled by the flags
+func (c *client) optionalColumns(id int32, streamId int32, sendTimestamp time.Time) []string {
+	var columns []string
+	if c.flaggingEnabled() {
+		columns = append(columns, c.messagePhase(id, sendTimestamp))
+	}
+	if c.profile != nil && c.profile.burstLength() != 0 {
+		if index, position, tagged := c.profile.burstTag(id); tagged {
+			columns = append(columns, strconv.FormatUint(index, 10), strconv.FormatUint(position, 10))
+		} else {
+			columns = append(columns, "", "")
+		}
+	}
+	if c.traceEntries != nil {
+		if id != 0 {
+			columns = append(columns, strconv.Itoa(c.traceEntryOf(id).line))
+		} else {
+			columns = append(columns, "")
+		}
+	}
+	if c.config.Streams > 1 {
+		columns = append(columns, strconv.Itoa(int(streamId)))
+	}
+	columns = append(columns, strconv.Itoa(int(id)))
+	return columns
+}
+
+// Return the offset from the start at which the message has to be sent, false if it is sent after the interval
+func (c *client) scheduledOffset(id int32) (time.Duration, bool) {
+	if c.traceEntries != nil {
+		return c.traceEntryOf(id).offset, true
+	}
+	if c.profile != nil {
+		return c.profile.offset(id, c.config.Reps), true
+	}
+	return 0, false
+}
+
+// Return the expected duration of the execution, 0 if it runs until interrupted
+func (c *client) executionDuration() time.Duration {
+	if c.config.Reps == 0 {
+		return 0
+	}
+	if offset, scheduled := c.scheduledOffset(int32(c.config.Reps) + 1); scheduled {
+		return offset
+	}
+	return time.Duration(c.config.Reps) * time.Duration(c.config.Interval) * time.Millisecond
+}
+
+// Return the phase of the execution the message was sent in
+func (c *client) messagePhase(id int32, sendTimestamp time.Time) string {
+	elapsed := sendTimestamp.Sub(c.sendStart)
+	if id != 0 && uint64(id) <= c.warmupWindow.messages || elapsed < c.warmupWindow.duration {
+		return WarmupPhase
+	}
+	if c.config.Reps != 0 {
+		if id != 0 && c.cooldownWindow.messages != 0 && uint64(id)+c.cooldownWindow.messages > c.config.Reps {
+			return CooldownPhase
+		}
+		if c.cooldownWindow.duration != 0 && elapsed >= c.executionDuration()-c.cooldownWindow.duration {
+			return CooldownPhase
+		}
+	}
+	return SteadyPhase
+}
+
+// True if the message is the last one before closing a cold connection
+func (c *client) coldConnectionEnd(id int32) bool {
+	return c.config.ReconnectEvery != 0 && id != 0 && uint64(id)%c.config.ReconnectEvery == 0
+}
+
+// Parse a comma separated list of idle gaps in milliseconds
+func parseIdleGaps(value string) ([]time.Duration, error) {
+	var gaps []time.Duration
+	if value == "" {
+		return gaps, nil
+	}
+	for _, gap := range strings.Split(value, ",") {
+		ms, err := strconv.ParseUint(strings.TrimSpace(gap), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid idle gap %q, expected milliseconds", gap)
+		}
+		gaps = append(gaps, time.Duration(ms)*time.Millisecond)
+	}
+	return gaps, nil
+}
+
+// Return the idle gap to wait after the message, if it is the last one of a burst followed by a gap
+func (c *client) idleGapAfter(id int32) (time.Duration, bool) {
+	if len(c.idleGapList) == 0 || uint64(id)%c.config.Burst != 0 {
+		return 0, false
+	}
+	index := uint64(id) / c.config.Burst
+	if index > uint64(len(c.idleGapList)) {
+		return 0, false
+	}
+	return c.idleGapList[index-1], true
+}
+
+func getTimestamp() time.Time {
+	return time.Now()
+}
+
+func (c *client) printLogs() {
+	fmt.Println("Repetitions:\t\t", c.config.Reps)
+	fmt.Println("Request Bytes:\t\t", c.config.RequestBytes)
+	fmt.Println("Response Bytes:\t\t", c.config.ResponseBytes)
+	fmt.Println("Payload pattern:\t", c.config.PayloadPattern)
+	fmt.Println("Processing delay:\t", c.config.ProcessingDelay)
+	fmt.Println("Send Interval:\t\t", c.config.Interval)
+	fmt.Println("Traffic profile:\t", c.config.Profile)
+	fmt.Println("Trace file:\t\t", c.config.TraceFile)
+	fmt.Println("Reconnect every:\t", c.config.ReconnectEvery)
+	fmt.Println("TLS resumption:\t\t", c.config.TlsResumption)
+	fmt.Println("Connections:\t\t", c.config.Connections)
+	fmt.Println("Streams:\t\t", c.config.Streams)
+	fmt.Println("Stream sizes:\t\t", c.config.StreamSizes)
+	fmt.Println("TLS enabled:\t\t", c.config.Tls)
+	fmt.Println("Traceroute IP:\t", c.config.TracerouteIp)
+	fmt.Println("TCP Stats enabled:\t", c.config.TcpStats)
+	fmt.Println("Host Stats period:\t", c.config.HostStats)
+	fmt.Println("Kernel timestamps:\t", c.config.KernelTimestamps)
+	fmt.Println("Server timestamps:\t", c.config.ServerTimestamps)
+	fmt.Println("Instance logging:\t", c.config.ServerInstance)
+	fmt.Println("Hops:\t\t\t", c.config.Hops)
+	fmt.Println("Detect faults:\t\t", c.config.DetectFaults)
+	fmt.Println("Injected faults:\t", c.config.InjectedFaults)
+	fmt.Println("Warm-up window:\t\t", c.config.Warmup)
+	fmt.Println("Cool-down window:\t", c.config.Cooldown)
+	fmt.Println("Idle gaps:\t\t", c.config.IdleGaps)
+	fmt.Println("Pinned CPUs:\t\t", c.config.Cpus)
+	fmt.Println("Thread priority:\t", c.config.Priority)
+	fmt.Println("Garbage collector:\t", c.config.Gc)
+	fmt.Println("Output format:\t\t", c.config.Format)
+	fmt.Println("Rotate size (MB):\t", c.config.RotateSize)
+	fmt.Println("Rotate every:\t\t", c.config.RotateEvery)
+	fmt.Println("Address:\t\t", c.config.Address)
+	fmt.Println()
+}
+
+func (c *client) getTCPConnFromWebsocketConn(conn *websocket.Conn) *net.TCPConn {
+	if tc := c.timestampingConnOf(conn); tc != nil {
+		return tc.TCPConn
+	}
+	if c.config.Tls {
+		return getConnFromTLSConn(conn.UnderlyingConn().(*tls.Conn)).(*net.TCPConn)
+	} else {
+		return conn.UnderlyingConn().(*net.TCPConn)
+	}
+}
+
+// to get the internal wrapped connection from the tls.Conn
+func getConnFromTLSConn(tlsConn *tls.Conn) net.Conn {
+	// awful workaround until https://github.com/golang/go/issues/29257 is solved.
+	conn := reflect.ValueOf(tlsConn).Elem().FieldByName("conn")
+	conn = reflect.NewAt(conn.Type(), unsafe.Pointer(conn.UnsafeAddr())).Elem()
+	return conn.Interface().(net.Conn)
+}
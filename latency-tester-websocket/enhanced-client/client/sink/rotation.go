@@ -0,0 +1,148 @@
+package sink
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Header of the index of the segments of a rotated results file
+const IndexHeader = "#segment,file,first-client-send-timestamp,last-client-send-timestamp,records,bytes,sha256"
+
+// Rotation of the results file into segments, whichever limit is reached first
+type Rotation struct {
+	Size  int64         // bytes after which a new segment is started, 0 to never rotate by size
+	Every time.Duration // time after which a new segment is started, 0 to never rotate by time
+}
+
+func (r Rotation) Enabled() bool {
+	return r.Size != 0 || r.Every != 0
+}
+
+// Sink writing the results in consecutive segments, listed in the index file once they are completed
+type rotatingSink struct {
+	sync.Mutex
+	format    string
+	prefix    string
+	extension string
+	columns   []string
+	rotation  Rotation
+	index     *os.File
+	current   *bufferedSink
+	number    int
+	start     time.Time
+	records   uint64
+	first     time.Time
+	last      time.Time
+}
+
+// Return the name of a segment of the results file with the given prefix
+func SegmentName(prefix string, number int, extension string) string {
+	return fmt.Sprintf("%s_segment-%04d%s", prefix, number, extension)
+}
+
+// Return the name of the index of the segments of the results file with the given prefix
+func IndexName(prefix string) string {
+	return prefix + "_segments.csv"
+}
+
+// Create the first segment and the index of a results file rotated according to the given limits. Each segment is
+// a complete file of the format, with its own header
+func CreateRotating(format string, prefix string, columns []string, rotation Rotation) (ResultSink, error) {
+	extension, err := Extension(format)
+	if err != nil {
+		return nil, err
+	}
+	index, err := os.Create(IndexName(prefix))
+	if err != nil {
+		return nil, err
+	}
+	if _, err = index.WriteString(IndexHeader + "\n"); err != nil {
+		index.Close()
+		return nil, err
+	}
+	r := &rotatingSink{
+		format:    format,
+		prefix:    prefix,
+		extension: extension,
+		columns:   columns,
+		rotation:  rotation,
+		index:     index,
+	}
+	if err = r.openSegment(); err != nil {
+		index.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingSink) Write(record Record) error {
+	r.Lock()
+	defer r.Unlock()
+	if r.records != 0 && (r.rotation.Size != 0 && r.current.written() >= r.rotation.Size ||
+		r.rotation.Every != 0 && time.Since(r.start) >= r.rotation.Every) {
+		if err := r.closeSegment(); err != nil {
+			return err
+		}
+		if err := r.openSegment(); err != nil {
+			return err
+		}
+	}
+	if err := r.current.Write(record); err != nil {
+		return err
+	}
+	if r.records == 0 {
+		r.first = record.ClientTimestamp
+	}
+	r.last = record.ClientTimestamp
+	r.records++
+	return nil
+}
+
+func (r *rotatingSink) Close() error {
+	r.Lock()
+	defer r.Unlock()
+	if err := r.closeSegment(); err != nil {
+		r.index.Close()
+		return err
+	}
+	return r.index.Close()
+}
+
+func (r *rotatingSink) openSegment() error {
+	r.number++
+	segment, err := create(r.format, SegmentName(r.prefix, r.number, r.extension), r.columns)
+	if err != nil {
+		return err
+	}
+	r.current = segment
+	r.start = time.Now()
+	r.records = 0
+	r.first = time.Time{}
+	r.last = time.Time{}
+	return nil
+}
+
+// Close the current segment and add it to the index, with the checksum computed while writing it
+func (r *rotatingSink) closeSegment() error {
+	if err := r.current.Close(); err != nil {
+		return err
+	}
+	filename := r.current.file.Name()
+	checksum, size := r.current.checksum(), r.current.written()
+	first, last := "", ""
+	if r.records != 0 {
+		first = strconv.FormatInt(r.first.UnixNano(), 10)
+		last = strconv.FormatInt(r.last.UnixNano(), 10)
+	}
+	_, err := r.index.WriteString(strconv.Itoa(r.number) + "," + filepath.Base(filename) + "," + first + "," + last + "," +
+		strconv.FormatUint(r.records, 10) + "," + strconv.FormatInt(size, 10) + "," + checksum + "\n")
+	if err != nil {
+		return err
+	}
+	// The index is kept consistent with the completed segments, in case the execution is killed
+	return r.index.Sync()
+}
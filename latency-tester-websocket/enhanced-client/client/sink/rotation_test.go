@@ -0,0 +1,103 @@
+package sink
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRotatingSink(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		rotation Rotation
+		records  int
+		segments int
+	}{
+		{"single segment", CsvFormat, Rotation{Size: 1 << 20}, 10, 1},
+		{"csv by size", CsvFormat, Rotation{Size: 200}, 20, 3},
+		{"jsonl by size", JsonLinesFormat, Rotation{Size: 300}, 12, 3},
+		{"protobuf by size", ProtobufFormat, Rotation{Size: 100}, 10, 4},
+		{"one record per segment", CsvFormat, Rotation{Size: 1}, 4, 4},
+		{"by time", CsvFormat, Rotation{Every: time.Hour}, 10, 1},
+		{"no records", CsvFormat, Rotation{Size: 100}, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "rotation")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			prefix := filepath.Join(dir, "results")
+			s, err := CreateRotating(tt.format, prefix, []string{"stream"}, tt.rotation)
+			if err != nil {
+				t.Fatal(err)
+			}
+			start := time.Unix(0, 1000)
+			for i := 0; i < tt.records; i++ {
+				record := Record{
+					ClientTimestamp: start.Add(time.Duration(i) * time.Millisecond),
+					ServerTimestamp: start.Add(time.Duration(i)*time.Millisecond + 500*time.Microsecond),
+					Rtt:             1.25,
+					Columns:         []string{"0"},
+				}
+				if err := s.Write(record); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := s.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			index, err := os.Open(IndexName(prefix))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer index.Close()
+			rows, err := csv.NewReader(index).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rows[0][0] != "#segment" || len(rows[0]) != 7 {
+				t.Errorf("index header = %v", rows[0])
+			}
+			rows = rows[1:]
+			if len(rows) != tt.segments {
+				t.Fatalf("segments = %d, want %d", len(rows), tt.segments)
+			}
+			total := 0
+			for i, row := range rows {
+				extension, _ := Extension(tt.format)
+				if want := filepath.Base(SegmentName(prefix, i+1, extension)); row[1] != want {
+					t.Errorf("segment %d file = %s, want %s", i+1, row[1], want)
+				}
+				content, err := ioutil.ReadFile(filepath.Join(dir, row[1]))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if row[5] != strconv.Itoa(len(content)) {
+					t.Errorf("segment %d bytes = %s, want %d", i+1, row[5], len(content))
+				}
+				sum := sha256.Sum256(content)
+				if row[6] != hex.EncodeToString(sum[:]) {
+					t.Errorf("segment %d checksum does not match its content", i+1)
+				}
+				records, _ := strconv.Atoi(row[4])
+				if records == 0 && (row[2] != "" || row[3] != "") {
+					t.Errorf("segment %d without records has timestamps %s-%s", i+1, row[2], row[3])
+				}
+				total += records
+			}
+			if total != tt.records {
+				t.Errorf("records = %d, want %d", total, tt.records)
+			}
+		})
+	}
+}
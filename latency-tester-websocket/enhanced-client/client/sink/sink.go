@@ -0,0 +1,232 @@
+// Package sink stores the results of the messages in a file, as csv, JSON lines or length-delimited protobuf records,
+// buffering the writes and flushing them periodically. For long executions, the file can be rotated into segments
+// listed in an index with their checksums.
+//
+// The package is imported by the MQTT client too.
+package sink
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/richiMarchi/latency-tester/enhanced-client/client/sink/serialization/protobuf"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	CsvFormat       = "csv"
+	JsonLinesFormat = "jsonl"
+	ProtobufFormat  = "protobuf"
+)
+
+// Interval the buffered records are written to the file at
+const FlushInterval = time.Second
+
+// Result of a message, with the values of the additional columns of the sink
+type Record struct {
+	ClientTimestamp time.Time
+	ServerTimestamp time.Time
+	Rtt             float64 // in milliseconds, -1 if not measured
+	Columns         []string
+}
+
+// Destination of the results
+type ResultSink interface {
+	Write(record Record) error
+	// Flush the buffered records and close the file
+	Close() error
+}
+
+type bufferedSink struct {
+	sync.Mutex
+	file    *os.File
+	writer  *bufio.Writer
+	columns []string
+	encode  func(w io.Writer, columns []string, record Record) error
+	stop    chan struct{}
+	// Bytes written to the file, including the buffered ones
+	size int64
+	// SHA-256 of the bytes written to the file, updated as they are flushed
+	hash hash.Hash
+}
+
+// Writer counting the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n *int64
+}
+
+func (cw countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	*cw.n += int64(n)
+	return n, err
+}
+
+// Return the extension of the files of the format
+func Extension(format string) (string, error) {
+	switch format {
+	case CsvFormat:
+		return ".csv", nil
+	case JsonLinesFormat:
+		return ".jsonl", nil
+	case ProtobufFormat:
+		return ".pb", nil
+	default:
+		return "", errors.New("unknown format " + strconv.Quote(format) + ", allowed ones are csv, jsonl and protobuf")
+	}
+}
+
+// Create the file of the sink, whose records have the given additional columns
+func Create(format string, filename string, columns []string) (ResultSink, error) {
+	return create(format, filename, columns)
+}
+
+func create(format string, filename string, columns []string) (*bufferedSink, error) {
+	if _, err := Extension(format); err != nil {
+		return nil, err
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	s := &bufferedSink{
+		file:    file,
+		columns: columns,
+		stop:    make(chan struct{}),
+		hash:    sha256.New(),
+	}
+	s.writer = bufio.NewWriter(io.MultiWriter(file, s.hash))
+	switch format {
+	case CsvFormat:
+		s.encode = encodeCsv
+		header := "#client-send-timestamp,server-timestamp,e2e-rtt"
+		for _, column := range columns {
+			header += "," + column
+		}
+		_, _ = io.WriteString(countingWriter{s.writer, &s.size}, header+"\n")
+	case JsonLinesFormat:
+		s.encode = encodeJsonLine
+	case ProtobufFormat:
+		s.encode = encodeProtobuf
+	}
+	go s.flushPeriodically()
+	return s, nil
+}
+
+func (s *bufferedSink) Write(record Record) error {
+	s.Lock()
+	defer s.Unlock()
+	return s.encode(countingWriter{s.writer, &s.size}, s.columns, record)
+}
+
+func (s *bufferedSink) Close() error {
+	close(s.stop)
+	s.Lock()
+	defer s.Unlock()
+	if err := s.writer.Flush(); err != nil {
+		s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
+
+// Return the bytes written to the file, including the buffered ones
+func (s *bufferedSink) written() int64 {
+	s.Lock()
+	defer s.Unlock()
+	return s.size
+}
+
+// Return the hex encoded SHA-256 checksum of the bytes flushed to the file, complete once the sink is closed
+func (s *bufferedSink) checksum() string {
+	s.Lock()
+	defer s.Unlock()
+	return hex.EncodeToString(s.hash.Sum(nil))
+}
+
+func (s *bufferedSink) flushPeriodically() {
+	ticker := time.NewTicker(FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Lock()
+			_ = s.writer.Flush()
+			s.Unlock()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func formatRtt(rtt float64) string {
+	return strconv.FormatFloat(rtt, 'f', -1, 64)
+}
+
+func encodeCsv(w io.Writer, _ []string, record Record) error {
+	line := strconv.FormatInt(record.ClientTimestamp.UnixNano(), 10) + "," +
+		strconv.FormatInt(record.ServerTimestamp.UnixNano(), 10) + "," + formatRtt(record.Rtt)
+	for _, value := range record.Columns {
+		line += "," + value
+	}
+	_, err := io.WriteString(w, line+"\n")
+	return err
+}
+
+// The keys are the csv column names with underscores, and the numeric values are written as numbers
+func encodeJsonLine(w io.Writer, columns []string, record Record) error {
+	line := map[string]interface{}{
+		"client_send_timestamp": record.ClientTimestamp.UnixNano(),
+		"server_timestamp":      record.ServerTimestamp.UnixNano(),
+		"e2e_rtt":               json.Number(formatRtt(record.Rtt)),
+	}
+	for i, column := range columns {
+		if i >= len(record.Columns) {
+			break
+		}
+		var value interface{} = record.Columns[i]
+		if _, err := strconv.ParseFloat(record.Columns[i], 64); err == nil {
+			value = json.Number(record.Columns[i])
+		}
+		line[strings.ReplaceAll(column, "-", "_")] = value
+	}
+	encoded, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(encoded, '\n'))
+	return err
+}
+
+// Each record is preceded by its length as a varint, as done by the delimited protobuf streams
+func encodeProtobuf(w io.Writer, columns []string, record Record) error {
+	message := &protobuf.Record{
+		ClientTimestamp: timestamppb.New(record.ClientTimestamp),
+		ServerTimestamp: timestamppb.New(record.ServerTimestamp),
+		E2ERtt:          record.Rtt,
+		Columns:         make(map[string]string),
+	}
+	for i, column := range columns {
+		if i < len(record.Columns) {
+			message.Columns[column] = record.Columns[i]
+		}
+	}
+	encoded, err := proto.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(protowire.AppendVarint(nil, uint64(len(encoded))), encoded...))
+	return err
+}
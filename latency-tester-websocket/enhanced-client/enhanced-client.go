@@ -18,6 +18,8 @@ import (
 	"time"
 
 	"github.com/lorenzosaino/go-sysctl"
+	"github.com/richiMarchi/latency-tester/enhanced-client/client/hoststats"
+	"github.com/richiMarchi/latency-tester/enhanced-client/client/prober"
 	"gopkg.in/yaml.v2"
 )
 
@@ -47,11 +49,37 @@ type Settings struct {
 	PingInterval      int            `yaml:"ping_interval"` // in seconds
 	SourcePort        int            `yaml:"source_port"`
 	Endpoints         []EndpointData `yaml:"endpoints"`
-	Intervals         []int          `yaml:"intervals"`     // in milliseconds
+	Intervals         []IntervalData `yaml:"intervals"`     // in milliseconds or as traffic profiles
 	MsgSizes          []int          `yaml:"msg_sizes"`     // in bytes
 	ResponseSize      int            `yaml:"response_size"` // in bytes
 	TcpdumpEnabled    bool           `yaml:"tcpdump_enabled"`
 	ExecDir           string         `yaml:"exec_dir"`
+	Warmup            string         `yaml:"warmup"`              // in messages or as a duration
+	Cooldown          string         `yaml:"cooldown"`            // in messages or as a duration
+	IdleGaps          []int          `yaml:"idle_gaps"`           // in milliseconds
+	IdleBurst         int            `yaml:"idle_burst"`          // messages sent before and after each gap
+	IdleBurstInterval int            `yaml:"idle_burst_interval"` // in milliseconds
+	IdleSlowStartSwap bool           `yaml:"idle_slow_start_swap"`
+	ReconnectEvery    int            `yaml:"reconnect_every"` // messages sent on each connection
+	TlsResumption     bool           `yaml:"tls_resumption"`
+	Connections       int            `yaml:"connections"` // parallel connections of each step
+	InProcessClient   bool           `yaml:"in_process_client"`
+	Streams           int            `yaml:"streams"`          // streams to compare, multiplexed and on separate connections
+	StreamsInterval   int            `yaml:"streams_interval"` // in milliseconds
+	StreamSizes       []string       `yaml:"stream_sizes"`     // <request>/<response> bytes of the other streams
+	RotateSize        int            `yaml:"rotate_size"`      // in megabytes
+	RotateEvery       string         `yaml:"rotate_every"`     // as a duration
+	Calibrate         bool           `yaml:"calibrate"`
+	CalibrationStep   int            `yaml:"calibration_step_duration"` // in seconds
+	ClientCpus        string         `yaml:"client_cpus"`               // comma separated
+	ClientPriority    string         `yaml:"client_priority"`           // rt:N or nice:N
+	ClientGc          string         `yaml:"client_gc"`                 // off or a percentage
+	HostStatsInterval int            `yaml:"host_stats_interval"`       // in milliseconds
+	ProcessingDelay   string         `yaml:"processing_delay"`          // model:parameters
+	ServerTimestamps  bool           `yaml:"server_timestamps"`
+	ServerInstance    bool           `yaml:"server_instance"`
+	Hops              bool           `yaml:"hops"`
+	DetectFaults      bool           `yaml:"detect_faults"`
 }
 
 const DataDirName = "raw-data/"
@@ -63,6 +91,17 @@ func main() {
 	if len(os.Args) == 1 {
 		log.Fatal(LoggerHdr + "Settings filename requested")
 	}
+	if os.Args[1] == NamespaceClientCommand {
+		namespaceClientCommand(os.Args[2:])
+		return
+	}
+	if os.Args[1] == "calibrate" {
+		if len(os.Args) < 4 {
+			log.Fatal(LoggerHdr + "Settings filename and campaign folder requested: calibrate <settings> <campaign-folder>")
+		}
+		calibrateCommand(os.Args[2], os.Args[3])
+		return
+	}
 
 	stopHealthChecker := make(chan os.Signal, 1)
 	log.Println(LoggerHdr + "Run Health Checker on '0.0.0.0:8080/health'")
@@ -157,6 +196,10 @@ func main() {
 
 	genParamsFile(settings)
 
+	if settings.Calibrate {
+		calibrate(settings, settings.ExecDir+DataDirName)
+	}
+
 	// Start ping and tcpdump in background
 	stopPing := make(chan os.Signal, len(settings.PingDestinations))
 	var wg sync.WaitGroup
@@ -167,6 +210,15 @@ func main() {
 	}
 
 	for i := 1; i <= settings.Runs; i++ {
+		// Sample the host load during the whole run
+		var hostStats *hoststats.Recorder
+		if settings.HostStatsInterval > 0 {
+			hostStats, err = hoststats.Start(settings.ExecDir+DataDirName+strconv.Itoa(i)+"-host-stats.csv",
+				time.Duration(settings.HostStatsInterval)*time.Millisecond)
+			if err != nil {
+				log.Println(LoggerHdr+"*** ERROR sampling the host load:", err)
+			}
+		}
 		// Handle Iperf
 		for _, iperfData := range settings.IperfDestinations {
 			if iperfData.Port == "" {
@@ -191,35 +243,66 @@ func main() {
 		for _, addr := range settings.Endpoints {
 			for _, inter := range settings.Intervals {
 				for _, size := range settings.MsgSizes {
-					repetitions := int((time.Duration(settings.RunsStepDuration) * time.Second).Milliseconds()) / inter
+					repetitions := inter.Repetitions(settings.RunsStepDuration)
 					log.Println(LoggerHdr + "Run: " + strconv.Itoa(i) + " - " +
 						"EP: " + addr.Destination + " - " +
-						"Inter: " + strconv.Itoa(inter) + " - " +
+						"Inter: " + inter.String() + " - " +
 						"Msg: " + strconv.Itoa(size))
-					clientCmd := exec.Command("./client",
-						"-reps="+strconv.Itoa(repetitions),
-						"-srcPort="+strconv.Itoa(settings.SourcePort),
-						"-interval="+strconv.Itoa(inter),
-						"-requestPayload="+strconv.Itoa(size),
-						"-responsePayload="+strconv.Itoa(settings.ResponseSize),
-						"-tls="+strconv.FormatBool(addr.TlsEnabled),
-						"-log="+settings.ExecDir+DataDirName+strconv.Itoa(i)+"-"+strings.ReplaceAll(addr.Destination, ":", "_")+
-							".i"+strconv.Itoa(inter)+".x"+strconv.Itoa(size),
-						addr.Destination)
-					var stdErrClient bytes.Buffer
-					clientCmd.Stderr = &stdErrClient
-					err = clientCmd.Run()
-					if err != nil {
-						log.Println(LoggerHdr+"*** ERROR executing client:", err)
-					} else {
-						log.Println(LoggerHdr + "OK! - Client executed successfully")
+					clientArgs := []string{
+						"-reps=" + strconv.Itoa(repetitions),
+						"-srcPort=" + strconv.Itoa(settings.SourcePort),
+						inter.ClientArg(),
+						"-requestPayload=" + strconv.Itoa(size),
+						"-responsePayload=" + strconv.Itoa(settings.ResponseSize),
+						"-tls=" + strconv.FormatBool(addr.TlsEnabled),
+						"-warmup=" + settings.Warmup,
+						"-cooldown=" + settings.Cooldown,
+						"-reconnectEvery=" + strconv.Itoa(settings.ReconnectEvery),
+						"-tlsResumption=" + strconv.FormatBool(settings.TlsResumption),
+						"-log=" + settings.ExecDir + DataDirName + strconv.Itoa(i) + "-" +
+							strings.ReplaceAll(addr.Destination, ":", "_") + ".i" + inter.Label() + ".x" + strconv.Itoa(size),
+					}
+					if settings.Connections > 1 {
+						clientArgs = append(clientArgs, "-connections="+strconv.Itoa(settings.Connections))
+					}
+					if settings.RotateSize > 0 || settings.RotateEvery != "" {
+						clientArgs = append(clientArgs, "-rotateSize="+strconv.Itoa(settings.RotateSize),
+							"-rotateEvery="+settings.RotateEvery)
 					}
-					if stdErrClient.Len() > 0 {
-						log.Println(LoggerHdr+"*** CLIENT STDERR ***\n", stdErrClient.String())
+					if settings.HostStatsInterval > 0 {
+						clientArgs = append(clientArgs, "-hostStats="+strconv.Itoa(settings.HostStatsInterval))
 					}
+					if settings.ProcessingDelay != "" {
+						clientArgs = append(clientArgs, "-processingDelay="+settings.ProcessingDelay)
+					}
+					if settings.ServerTimestamps {
+						clientArgs = append(clientArgs, "-serverTimestamps=true")
+					}
+					if settings.ServerInstance {
+						clientArgs = append(clientArgs, "-serverInstance=true")
+					}
+					if settings.Hops {
+						clientArgs = append(clientArgs, "-hops=true")
+					}
+					if settings.DetectFaults {
+						clientArgs = append(clientArgs, "-detectFaults=true")
+					}
+					clientArgs = append(clientArgs, lowNoiseArgs(settings)...)
+					runClient(append(clientArgs, addr.Destination), settings.InProcessClient)
 				}
 			}
 		}
+		// Start idle-gap analysis
+		if len(settings.IdleGaps) > 0 {
+			idleGapSweep(i, settings, ss)
+		}
+		// Start streams comparison
+		if settings.Streams > 1 {
+			streamsSweep(i, settings)
+		}
+		if hostStats != nil {
+			hostStats.Stop()
+		}
 		if settings.TcpdumpEnabled {
 			log.Println(LoggerHdr + "Signal Tcpdump Stop")
 			stopTcpdump <- os.Interrupt
@@ -264,6 +347,187 @@ func main() {
 	log.Println(LoggerHdr + "Everything's complete!")
 }
 
+// Return the client arguments of the low-noise mode, if requested
+func lowNoiseArgs(settings Settings) []string {
+	var args []string
+	if settings.ClientCpus != "" {
+		args = append(args, "-cpus="+settings.ClientCpus)
+	}
+	if settings.ClientPriority != "" {
+		args = append(args, "-priority="+settings.ClientPriority)
+	}
+	if settings.ClientGc != "" {
+		args = append(args, "-gc="+settings.ClientGc)
+	}
+	return args
+}
+
+// Execute the client with the given arguments and log its outcome
+func runClient(args []string, inProcess bool) {
+	const LoggerHdr = "@runClient     - "
+
+	if inProcess {
+		err := runProber(args)
+		if err != nil {
+			log.Println(LoggerHdr+"*** ERROR executing client:", err)
+		} else {
+			log.Println(LoggerHdr + "OK! - Client executed successfully")
+		}
+		return
+	}
+	clientCmd := exec.Command("./client", args...)
+	var stdErrClient bytes.Buffer
+	clientCmd.Stderr = &stdErrClient
+	err := clientCmd.Run()
+	if err != nil {
+		log.Println(LoggerHdr+"*** ERROR executing client:", err)
+	} else {
+		log.Println(LoggerHdr + "OK! - Client executed successfully")
+	}
+	if stdErrClient.Len() > 0 {
+		log.Println(LoggerHdr+"*** CLIENT STDERR ***\n", stdErrClient.String())
+	}
+}
+
+// Run the measurement of the client inside the enhanced client process, with the same arguments of the command
+func runProber(args []string) error {
+	config, err := prober.ConfigFromArgs(args)
+	if err != nil {
+		return err
+	}
+	p, err := prober.New(config)
+	if err != nil {
+		return err
+	}
+	if err = p.Connect(); err != nil {
+		return err
+	}
+	return p.Run(context.Background())
+}
+
+// Run the client in idle-gap mode towards every endpoint for every message size. If requested, the sweep is repeated
+// with both values of net.ipv4.tcp_slow_start_after_idle, restoring the original one at the end.
+func idleGapSweep(run int, settings Settings, originalSlowStart string) {
+	const LoggerHdr = "@idleGapSweep  - "
+
+	if settings.IdleBurst == 0 {
+		settings.IdleBurst = 10
+	}
+	if settings.IdleBurstInterval == 0 {
+		settings.IdleBurstInterval = 10
+	}
+	gaps := ""
+	for i, gap := range settings.IdleGaps {
+		if i != 0 {
+			gaps += ","
+		}
+		gaps += strconv.Itoa(gap)
+	}
+
+	slowStartValues := []string{originalSlowStart}
+	if settings.IdleSlowStartSwap {
+		slowStartValues = []string{"1", "0"}
+	}
+	// The sysctl belongs to the network namespace of the container, but changing it requires the container to be
+	// privileged (or to have NET_ADMIN and a writable /proc/sys). Without privileges, every client is run in a network
+	// namespace of its own instead, so that all the values are measured the same way
+	inNamespace := false
+	if settings.IdleSlowStartSwap {
+		if err := sysctl.Set("net.ipv4.tcp_slow_start_after_idle", slowStartValues[0]); err != nil {
+			log.Println(LoggerHdr+"Cannot set TCP slow start after idle, running the clients in their own network "+
+				"namespace:", err)
+			inNamespace = true
+		}
+	}
+	for _, slowStart := range slowStartValues {
+		if settings.IdleSlowStartSwap && !inNamespace {
+			err := sysctl.Set("net.ipv4.tcp_slow_start_after_idle", slowStart)
+			if err != nil {
+				log.Println(LoggerHdr+"WARNING: Cannot set TCP slow start after idle, skipping value "+slowStart+":", err)
+				continue
+			}
+			log.Println(LoggerHdr + "TCP slow start after idle set to " + slowStart)
+		}
+		for _, addr := range settings.Endpoints {
+			for _, size := range settings.MsgSizes {
+				log.Println(LoggerHdr + "Run: " + strconv.Itoa(run) + " - " +
+					"EP: " + addr.Destination + " - " +
+					"Slow start after idle: " + slowStart + " - " +
+					"Msg: " + strconv.Itoa(size))
+				args := []string{
+					"-idleGaps=" + gaps,
+					"-burst=" + strconv.Itoa(settings.IdleBurst),
+					"-srcPort=" + strconv.Itoa(settings.SourcePort),
+					"-interval=" + strconv.Itoa(settings.IdleBurstInterval),
+					"-requestPayload=" + strconv.Itoa(size),
+					"-responsePayload=" + strconv.Itoa(settings.ResponseSize),
+					"-tls=" + strconv.FormatBool(addr.TlsEnabled),
+					"-log=" + settings.ExecDir + DataDirName + strconv.Itoa(run) + "-idle-ss" + slowStart + "-" +
+						strings.ReplaceAll(addr.Destination, ":", "_") + ".x" + strconv.Itoa(size),
+					addr.Destination,
+				}
+				if inNamespace {
+					runClientInNamespace(args, slowStart, settings.InProcessClient)
+				} else {
+					runClient(args, settings.InProcessClient)
+				}
+			}
+		}
+	}
+	if settings.IdleSlowStartSwap && !inNamespace && originalSlowStart != "" {
+		err := sysctl.Set("net.ipv4.tcp_slow_start_after_idle", originalSlowStart)
+		if err != nil {
+			log.Println(LoggerHdr+"*** ERROR restoring TCP slow start after idle:", err)
+		} else {
+			log.Println(LoggerHdr + "TCP slow start after idle restored to " + originalSlowStart)
+		}
+	}
+}
+
+// Run the client towards every endpoint for every message size, first with the streams multiplexed over a single
+// connection and then with each stream on its own connection, in order to compare them
+func streamsSweep(run int, settings Settings) {
+	const LoggerHdr = "@streamsSweep  - "
+
+	if settings.StreamsInterval == 0 {
+		settings.StreamsInterval = 10
+	}
+	repetitions := int((time.Duration(settings.RunsStepDuration) * time.Second).Milliseconds()) /
+		settings.StreamsInterval
+	modes := map[string]string{
+		"mux":  "-streams=" + strconv.Itoa(settings.Streams),
+		"conn": "-connections=" + strconv.Itoa(settings.Streams),
+	}
+	for _, mode := range []string{"mux", "conn"} {
+		for _, addr := range settings.Endpoints {
+			for _, size := range settings.MsgSizes {
+				log.Println(LoggerHdr + "Run: " + strconv.Itoa(run) + " - " +
+					"EP: " + addr.Destination + " - " +
+					"Streams: " + strconv.Itoa(settings.Streams) + " " + mode + " - " +
+					"Msg: " + strconv.Itoa(size))
+				args := []string{
+					modes[mode],
+					"-reps=" + strconv.Itoa(repetitions),
+					"-srcPort=" + strconv.Itoa(settings.SourcePort),
+					"-interval=" + strconv.Itoa(settings.StreamsInterval),
+					"-requestPayload=" + strconv.Itoa(size),
+					"-responsePayload=" + strconv.Itoa(settings.ResponseSize),
+					"-tls=" + strconv.FormatBool(addr.TlsEnabled),
+					"-log=" + settings.ExecDir + DataDirName + strconv.Itoa(run) + "-streams-" + mode + "-" +
+						strings.ReplaceAll(addr.Destination, ":", "_") + ".x" + strconv.Itoa(size),
+				}
+				// The first stream carries the message size of the step, the other ones the configured sizes, so
+				// that the large messages of a stream can block the small ones of the others
+				if len(settings.StreamSizes) > 0 {
+					args = append(args, "-streamSizes="+strconv.Itoa(size)+"/"+strconv.Itoa(settings.ResponseSize)+
+						","+strings.Join(settings.StreamSizes, ","))
+				}
+				runClient(append(args, addr.Destination), settings.InProcessClient)
+			}
+		}
+	}
+}
+
 func genParamsFile(settings Settings) {
 	const LoggerHdr = "@genParamsFile - "
 	log.Println(LoggerHdr + "Generating parameters file")
@@ -288,7 +552,7 @@ func genParamsFile(settings Settings) {
 		if i != 0 {
 			intervals += ","
 		}
-		intervals += strconv.Itoa(inter)
+		intervals += inter.String()
 	}
 	paramsFile.WriteString(intervals + "\n")
 	sizes := ""
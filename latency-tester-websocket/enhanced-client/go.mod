@@ -4,7 +4,11 @@ go 1.14
 
 require (
 	github.com/lorenzosaino/go-sysctl v0.1.1
-	gonum.org/v1/gonum v0.8.1
-	gonum.org/v1/plot v0.8.1
-	gopkg.in/yaml.v2 v2.3.0
+	github.com/richiMarchi/latency-tester/enhanced-client/client v0.0.0
+	github.com/richiMarchi/latency-tester/server v0.0.0
+	gopkg.in/yaml.v2 v2.4.0
 )
+
+replace github.com/richiMarchi/latency-tester/enhanced-client/client => ./client
+
+replace github.com/richiMarchi/latency-tester/server => ../server
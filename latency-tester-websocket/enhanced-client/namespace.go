@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/lorenzosaino/go-sysctl"
+)
+
+// Command running a client inside the network namespace created for it by the enhanced client, not meant to be used
+// directly
+const NamespaceClientCommand = "namespace-client"
+
+// Interface connecting the network namespace of the client to the one of the enhanced client
+const NamespaceTap = "tap0"
+
+// Time the network of the namespace is waited for before giving up
+const NamespaceSetupTimeout = 10 * time.Second
+
+// Run the client in a new user and network namespace, where net.ipv4.tcp_slow_start_after_idle can be set without
+// privileges. The namespace reaches the endpoints through slirp4netns, which is stopped once the client is done
+func runClientInNamespace(args []string, slowStart string, inProcess bool) {
+	const LoggerHdr = "@runClient     - "
+
+	clientCmd := exec.Command("/proc/self/exe",
+		append([]string{NamespaceClientCommand, slowStart, strconv.FormatBool(inProcess)}, args...)...)
+	clientCmd.Stdout = os.Stdout
+	clientCmd.Stderr = os.Stderr
+	clientCmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:  syscall.CLONE_NEWUSER | syscall.CLONE_NEWNET,
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+	}
+	if err := clientCmd.Start(); err != nil {
+		log.Println(LoggerHdr+"*** ERROR creating the network namespace of the client:", err)
+		return
+	}
+	networkCmd := exec.Command("slirp4netns", "--configure", "--mtu=65520",
+		strconv.Itoa(clientCmd.Process.Pid), NamespaceTap)
+	var stdErrNetwork bytes.Buffer
+	networkCmd.Stderr = &stdErrNetwork
+	if err := networkCmd.Start(); err != nil {
+		log.Println(LoggerHdr+"*** ERROR connecting the network namespace of the client:", err)
+		_ = clientCmd.Process.Kill()
+		_ = clientCmd.Wait()
+		return
+	}
+	err := clientCmd.Wait()
+	_ = networkCmd.Process.Kill()
+	_ = networkCmd.Wait()
+	if err != nil {
+		log.Println(LoggerHdr+"*** ERROR executing client in its network namespace:", err)
+		if stdErrNetwork.Len() > 0 {
+			log.Println(LoggerHdr+"*** SLIRP4NETNS STDERR ***\n", stdErrNetwork.String())
+		}
+	}
+}
+
+// Run the namespace-client command: wait for the network of the namespace, set the requested value of TCP slow start
+// after idle and run the client with the remaining arguments
+func namespaceClientCommand(args []string) {
+	const LoggerHdr = "@namespace     - "
+
+	if len(args) < 2 {
+		log.Fatal(LoggerHdr + "Slow start after idle and client mode requested: " + NamespaceClientCommand +
+			" <slow-start> <in-process> <client-args>")
+	}
+	if err := waitDefaultRoute(NamespaceSetupTimeout); err != nil {
+		log.Fatal(LoggerHdr+"*** ERROR setting up the network namespace:", err)
+	}
+	if err := sysctl.Set("net.ipv4.tcp_slow_start_after_idle", args[0]); err != nil {
+		log.Fatal(LoggerHdr+"*** ERROR setting TCP slow start after idle in the network namespace:", err)
+	}
+	log.Println(LoggerHdr + "TCP slow start after idle set to " + args[0] + " in the network namespace of the client")
+	runClient(args[2:], args[1] == "true")
+}
+
+// Wait for the default route of the network namespace, added by slirp4netns once the interface is configured
+func waitDefaultRoute(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		routes, err := ioutil.ReadFile("/proc/net/route")
+		if err != nil {
+			return err
+		}
+		for _, line := range strings.Split(string(routes), "\n")[1:] {
+			if fields := strings.Fields(line); len(fields) > 1 && fields[1] == "00000000" {
+				return nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return errors.New("no default route after " + timeout.String())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
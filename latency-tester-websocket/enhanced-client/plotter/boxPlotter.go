@@ -27,16 +27,17 @@ func typedBoxPlots(settings Settings, objectType int, wg *sync.WaitGroup) {
 			case ENDPOINTS:
 				plots[i][j], tmpMin, tmpMax = intXsizeBoxPlot(settings.MsgSizes[i], settings.Intervals[j], settings.Endpoints,
 					settings.ExecDir, settings.PercentilesToRemove, settings.WhiskerMin, settings.WhiskerMax,
-					requestedSlice(settings))
+					requestedSlice(settings), settings.FlaggedIncluded)
 				filename = "endpointsBoxPlot"
 			case INTERVALS:
 				plots[i][j], tmpMin, tmpMax = sizeXepBoxPlot(settings.Endpoints[i], settings.MsgSizes[j], settings.Intervals,
-					settings.ExecDir, settings.PercentilesToRemove, settings.WhiskerMin, settings.WhiskerMax, requestedSlice(settings))
+					settings.ExecDir, settings.PercentilesToRemove, settings.WhiskerMin, settings.WhiskerMax, requestedSlice(settings),
+					settings.FlaggedIncluded)
 				filename = "intervalsBoxPlot"
 			case SIZES:
 				plots[i][j], tmpMin, tmpMax = intXepBoxPlot(settings.Endpoints[i], settings.Intervals[j], settings.MsgSizes,
 					settings.ExecDir, settings.PercentilesToRemove, settings.WhiskerMin, settings.WhiskerMax,
-					requestedSlice(settings))
+					requestedSlice(settings), settings.FlaggedIncluded)
 				filename = "sizesBoxPlot"
 			default:
 				panic("Wrong objectType in loop elements: only values 0,1 and 2 are allowed")
@@ -68,36 +69,42 @@ func typedBoxPlots(settings Settings, objectType int, wg *sync.WaitGroup) {
 
 // Return a boxplot of the e2e rtt of the sizes given the interval and the endpoint
 func intXepBoxPlot(ep EndpointData,
-	si int,
+	si IntervalData,
 	msgSizes []int,
 	execdir string,
 	percentilesToRemove int,
 	whiskerMin int,
 	whiskerMax int,
-	requestedRuns []int) (*plot.Plot, float64, float64) {
-	log.Println(LoggerHdr + "Plot for " + ep.Description + " and send interval " + strconv.Itoa(si))
+	requestedRuns []int,
+	flaggedIncluded bool) (*plot.Plot, float64, float64) {
+	log.Println(LoggerHdr + "Plot for " + ep.Description + " and send interval " + si.String())
 	p, err := plot.New()
 	errMgmt(err)
 
 	// Open the desired files
 	openFiles := openDesiredFiles(execdir, requestedRuns,
-		"-"+strings.ReplaceAll(ep.Destination, ":", "_")+".i"+strconv.Itoa(si)+".x")
+		"-"+strings.ReplaceAll(ep.Destination, ":", "_")+".i"+si.Label()+".x")
 
 	valuesMap := make(map[int]plotter.Values)
 
 	for _, f := range openFiles {
 		filename := filenameOnly(f.Name())
+		if !isRttFile(filename) {
+			continue
+		}
 		parsedSizeVal, err := strconv.ParseInt(filename[strings.LastIndex(filename, "x")+1:len(filename)-4], 10, 32)
 		sizeVal := int(parsedSizeVal)
 		errMgmt(err)
 		if intInSlice(sizeVal, msgSizes) {
 			records, _ := csv.NewReader(f).ReadAll()
+			phaseColumn := headerColumn(records, "phase")
 			for i, row := range records {
-				if i != 0 {
+				if i != 0 && !isExcludedRecord(row, phaseColumn, flaggedIncluded) {
 					parsed, fail := strconv.ParseFloat(row[2], 64)
 					if fail != nil {
 						continue
 					}
+					parsed = correctedRtt(parsed, filename)
 					valuesMap[sizeVal] = append(valuesMap[sizeVal], parsed)
 				}
 			}
@@ -109,21 +116,22 @@ func intXepBoxPlot(ep EndpointData,
 	p.X.Label.Text = "Request Size (B)"
 	p.Y.Label.Text = "E2E RTT (ms)"
 	p.Y.Tick.Marker = hplot.Ticks{N: AxisTicks}
-	p.Title.Text = ep.Description + " - " + strconv.Itoa(si) + "ms"
+	p.Title.Text = ep.Description + " - " + si.Title()
 	configurePlotFontSizesMultiple(p, true)
 
-	return generateIntBoxPlotAndLimits(p, &valuesMap, percentilesToRemove, whiskerMin, whiskerMax)
+	return generateIntBoxPlotAndLimits(p, &valuesMap, strconv.Itoa, percentilesToRemove, whiskerMin, whiskerMax)
 }
 
 // Return a boxplot of the e2e rtt of the intervals given the size and the endpoint
 func sizeXepBoxPlot(ep EndpointData,
 	msgSize int,
-	sis []int,
+	sis []IntervalData,
 	execdir string,
 	percentilesToRemove int,
 	whiskerMin int,
 	whiskerMax int,
-	requestedRuns []int) (*plot.Plot, float64, float64) {
+	requestedRuns []int,
+	flaggedIncluded bool) (*plot.Plot, float64, float64) {
 	log.Println(LoggerHdr + "Plot for message size " + strconv.Itoa(msgSize) + " and endpoint " + ep.Description)
 	p, err := plot.New()
 	errMgmt(err)
@@ -136,18 +144,17 @@ func sizeXepBoxPlot(ep EndpointData,
 
 	for _, f := range openFiles {
 		filename := filenameOnly(f.Name())
-		parsedInterVal, err := strconv.ParseInt(
-			filename[strings.LastIndex(filename, ".i")+2:strings.LastIndex(filename, ".x")], 10, 32)
-		interVal := int(parsedInterVal)
-		errMgmt(err)
-		if intInSlice(interVal, sis) {
+		interVal := intervalIndex(filename[strings.LastIndex(filename, ".i")+2:strings.LastIndex(filename, ".x")], sis)
+		if interVal != -1 {
 			records, _ := csv.NewReader(f).ReadAll()
+			phaseColumn := headerColumn(records, "phase")
 			for i, row := range records {
-				if i != 0 {
+				if i != 0 && !isExcludedRecord(row, phaseColumn, flaggedIncluded) {
 					parsed, fail := strconv.ParseFloat(row[2], 64)
 					if fail != nil {
 						continue
 					}
+					parsed = correctedRtt(parsed, filename)
 					valuesMap[interVal] = append(valuesMap[interVal], parsed)
 				}
 			}
@@ -162,24 +169,25 @@ func sizeXepBoxPlot(ep EndpointData,
 	p.Title.Text = ep.Description + " - " + strconv.Itoa(msgSize) + "B"
 	configurePlotFontSizesMultiple(p, true)
 
-	return generateIntBoxPlotAndLimits(p, &valuesMap, percentilesToRemove, whiskerMin, whiskerMax)
+	return generateIntBoxPlotAndLimits(p, &valuesMap, func(k int) string { return sis[k].String() }, percentilesToRemove, whiskerMin, whiskerMax)
 }
 
 // Return a boxplot of the e2e rtt of the endpoints given the interval and the size
 func intXsizeBoxPlot(msgSize int,
-	si int,
+	si IntervalData,
 	eps []EndpointData,
 	execdir string,
 	percentilesToRemove int,
 	whiskerMin int,
 	whiskerMax int,
-	requestedRuns []int) (*plot.Plot, float64, float64) {
-	log.Println(LoggerHdr + "Plot for interval " + strconv.Itoa(si) + " and message size " + strconv.Itoa(msgSize))
+	requestedRuns []int,
+	flaggedIncluded bool) (*plot.Plot, float64, float64) {
+	log.Println(LoggerHdr + "Plot for interval " + si.String() + " and message size " + strconv.Itoa(msgSize))
 	p, err := plot.New()
 	errMgmt(err)
 
 	// Open the desired files
-	openFiles := openDesiredFiles(execdir, requestedRuns, ".i"+strconv.Itoa(si)+".x"+strconv.Itoa(msgSize)+".csv")
+	openFiles := openDesiredFiles(execdir, requestedRuns, ".i"+si.Label()+".x"+strconv.Itoa(msgSize)+".csv")
 
 	valuesMap := make(map[string]plotter.Values)
 
@@ -189,12 +197,14 @@ func intXsizeBoxPlot(msgSize int,
 		description, present := nameFromDest(parsedSizeVal, &eps)
 		if present {
 			records, _ := csv.NewReader(f).ReadAll()
+			phaseColumn := headerColumn(records, "phase")
 			for i, row := range records {
-				if i != 0 {
+				if i != 0 && !isExcludedRecord(row, phaseColumn, flaggedIncluded) {
 					parsed, fail := strconv.ParseFloat(row[2], 64)
 					if fail != nil {
 						continue
 					}
+					parsed = correctedRtt(parsed, filename)
 					valuesMap[description] = append(valuesMap[description], parsed)
 				}
 			}
@@ -206,8 +216,464 @@ func intXsizeBoxPlot(msgSize int,
 	p.X.Label.Text = "Endpoint"
 	p.Y.Label.Text = "E2E RTT (ms)"
 	p.Y.Tick.Marker = hplot.Ticks{N: AxisTicks}
-	p.Title.Text = strconv.Itoa(si) + "ms - " + strconv.Itoa(msgSize) + "B"
+	p.Title.Text = si.Title() + " - " + strconv.Itoa(msgSize) + "B"
 	configurePlotFontSizesMultiple(p, true)
 
 	return generateStringBoxPlotAndLimits(p, &valuesMap, percentilesToRemove, whiskerMin, whiskerMax)
 }
+
+// Plot the rtt of the first message after each idle gap, for every endpoint, slow start after idle value and size
+func idleGapBoxPlots(settings Settings, wg *sync.WaitGroup) {
+	slowStartValues := idleSlowStartValues(settings.ExecDir)
+	rows := len(settings.Endpoints) * len(slowStartValues)
+	cols := len(settings.MsgSizes)
+	if rows == 0 {
+		log.Println(LoggerHdr + "No idle-gap files found, so it will generate no idleGapsBoxPlot")
+		wg.Done()
+		return
+	}
+	min := math.Inf(1)
+	max := math.Inf(-1)
+	plots := make([][]*plot.Plot, rows)
+	for i := 0; i < rows; i++ {
+		plots[i] = make([]*plot.Plot, cols)
+		for j := 0; j < cols; j++ {
+			var tmpMin, tmpMax float64
+			plots[i][j], tmpMin, tmpMax = idleGapBoxPlot(settings.Endpoints[i/len(slowStartValues)],
+				slowStartValues[i%len(slowStartValues)], settings.MsgSizes[j], settings.ExecDir,
+				settings.PercentilesToRemove, settings.WhiskerMin, settings.WhiskerMax, requestedSlice(settings))
+			min = floats.Min([]float64{min, tmpMin})
+			max = floats.Max([]float64{max, tmpMax})
+		}
+	}
+
+	if settings.RttMin != 0 {
+		min = settings.RttMin
+	}
+	if settings.RttMax != 0 {
+		max = settings.RttMax
+	}
+	if !settings.EqualizationDisabled {
+		adjustMinMaxY(plots, rows, cols, min, max)
+	}
+	commonPlotting(plots, rows, cols, 100+cols*len(settings.IdleGaps)*200,
+		settings.ExecDir+PlotDirName+"idleGapsBoxPlot")
+
+	wg.Done()
+}
+
+// Return a boxplot of the e2e rtt of the first message after each idle gap given the endpoint, the slow start after
+// idle value and the size
+func idleGapBoxPlot(ep EndpointData,
+	slowStart string,
+	msgSize int,
+	execdir string,
+	percentilesToRemove int,
+	whiskerMin int,
+	whiskerMax int,
+	requestedRuns []int) (*plot.Plot, float64, float64) {
+	log.Println(LoggerHdr + "Idle-gap plot for " + ep.Description + ", slow start after idle " + slowStart +
+		" and message size " + strconv.Itoa(msgSize))
+	p, err := plot.New()
+	errMgmt(err)
+
+	// Open the desired files
+	openFiles := openDesiredFiles(execdir, requestedRuns, "-idle-ss"+slowStart+"-"+
+		strings.ReplaceAll(ep.Destination, ":", "_")+".x"+strconv.Itoa(msgSize)+"_idle-gaps.csv")
+
+	valuesMap := make(map[int]plotter.Values)
+
+	for _, f := range openFiles {
+		records, _ := csv.NewReader(f).ReadAll()
+		for i, row := range records {
+			if i != 0 {
+				gap, fail := strconv.Atoi(row[0])
+				if fail != nil {
+					continue
+				}
+				parsed, fail := strconv.ParseFloat(row[2], 64)
+				if fail != nil {
+					continue
+				}
+				valuesMap[gap] = append(valuesMap[gap], parsed)
+			}
+		}
+	}
+
+	closeOpenFiles(openFiles)
+
+	p.X.Label.Text = "Idle Gap (ms)"
+	p.Y.Label.Text = "First Message E2E RTT (ms)"
+	p.Y.Tick.Marker = hplot.Ticks{N: AxisTicks}
+	p.Title.Text = ep.Description + " - " + strconv.Itoa(msgSize) + "B - Slow start after idle: " + slowStart
+	configurePlotFontSizesMultiple(p, true)
+
+	return generateIntBoxPlotAndLimits(p, &valuesMap, strconv.Itoa, percentilesToRemove, whiskerMin, whiskerMax)
+}
+
+// Plot the connection setup times and the rtt of the first message of each cold connection, for every endpoint and size
+func coldConnectionBoxPlots(settings Settings, wg *sync.WaitGroup) {
+	rows := len(settings.Endpoints)
+	cols := len(settings.MsgSizes)
+	min := math.Inf(1)
+	max := math.Inf(-1)
+	plots := make([][]*plot.Plot, rows)
+	for i := 0; i < rows; i++ {
+		plots[i] = make([]*plot.Plot, cols)
+		for j := 0; j < cols; j++ {
+			var tmpMin, tmpMax float64
+			plots[i][j], tmpMin, tmpMax = coldConnectionBoxPlot(settings.Endpoints[i], settings.MsgSizes[j],
+				settings.ExecDir, settings.PercentilesToRemove, settings.WhiskerMin, settings.WhiskerMax,
+				requestedSlice(settings))
+			min = floats.Min([]float64{min, tmpMin})
+			max = floats.Max([]float64{max, tmpMax})
+		}
+	}
+
+	if !settings.EqualizationDisabled {
+		adjustMinMaxY(plots, rows, cols, min, max)
+	}
+	commonPlotting(plots, rows, cols, 100+cols*len(coldConnectionSeries)*200,
+		settings.ExecDir+PlotDirName+"coldConnectionsBoxPlot")
+
+	wg.Done()
+}
+
+// Return a boxplot of the tcp setup, the whole setup and the first message rtt of the cold connections given the
+// endpoint and the size, considering all the intervals
+func coldConnectionBoxPlot(ep EndpointData,
+	msgSize int,
+	execdir string,
+	percentilesToRemove int,
+	whiskerMin int,
+	whiskerMax int,
+	requestedRuns []int) (*plot.Plot, float64, float64) {
+	log.Println(LoggerHdr + "Cold connections plot for " + ep.Description + " and message size " + strconv.Itoa(msgSize))
+	p, err := plot.New()
+	errMgmt(err)
+
+	// Open the desired files
+	openFiles := openDesiredFiles(execdir, requestedRuns, "-"+strings.ReplaceAll(ep.Destination, ":", "_")+".i",
+		".x"+strconv.Itoa(msgSize)+"_cold-connections.csv")
+
+	valuesMap := make(map[int]plotter.Values)
+
+	for _, f := range openFiles {
+		records, _ := csv.NewReader(f).ReadAll()
+		for i, row := range records {
+			if i != 0 {
+				for series := range coldConnectionSeries {
+					parsed, fail := strconv.ParseFloat(row[series+1], 64)
+					if fail != nil {
+						continue
+					}
+					valuesMap[series] = append(valuesMap[series], parsed)
+				}
+			}
+		}
+	}
+
+	closeOpenFiles(openFiles)
+
+	p.Y.Label.Text = "Time (ms)"
+	p.Y.Tick.Marker = hplot.Ticks{N: AxisTicks}
+	p.Title.Text = ep.Description + " - " + strconv.Itoa(msgSize) + "B"
+	configurePlotFontSizesMultiple(p, true)
+
+	return generateIntBoxPlotAndLimits(p, &valuesMap, func(series int) string {
+		return coldConnectionSeries[series]
+	}, percentilesToRemove, whiskerMin, whiskerMax)
+}
+
+// Plot the decomposition of the rtt into uplink, server residence and downlink, for every endpoint and size, from the
+// server timestamps of the results or from the server logs
+func rttDecompositionBoxPlots(settings Settings, wg *sync.WaitGroup) {
+	rows := len(settings.Endpoints)
+	cols := len(settings.MsgSizes)
+	min := math.Inf(1)
+	max := math.Inf(-1)
+	plots := make([][]*plot.Plot, rows)
+	for i := 0; i < rows; i++ {
+		plots[i] = make([]*plot.Plot, cols)
+		for j := 0; j < cols; j++ {
+			var tmpMin, tmpMax float64
+			plots[i][j], tmpMin, tmpMax = rttDecompositionBoxPlot(settings.Endpoints[i], settings.MsgSizes[j],
+				settings.ExecDir, settings.PercentilesToRemove, settings.WhiskerMin, settings.WhiskerMax,
+				requestedSlice(settings), settings.FlaggedIncluded)
+			min = floats.Min([]float64{min, tmpMin})
+			max = floats.Max([]float64{max, tmpMax})
+		}
+	}
+
+	if !settings.EqualizationDisabled {
+		adjustMinMaxY(plots, rows, cols, min, max)
+	}
+	commonPlotting(plots, rows, cols, 100+cols*len(decompositionSeries)*200,
+		settings.ExecDir+PlotDirName+"rttDecompositionBoxPlot")
+
+	wg.Done()
+}
+
+// Return a boxplot of the uplink, server residence, downlink and network times of the messages given the endpoint and
+// the size, considering all the intervals
+func rttDecompositionBoxPlot(ep EndpointData,
+	msgSize int,
+	execdir string,
+	percentilesToRemove int,
+	whiskerMin int,
+	whiskerMax int,
+	requestedRuns []int,
+	flaggedIncluded bool) (*plot.Plot, float64, float64) {
+	log.Println(LoggerHdr + "RTT decomposition plot for " + ep.Description + " and message size " +
+		strconv.Itoa(msgSize))
+	p, err := plot.New()
+	errMgmt(err)
+
+	// Open the desired files
+	openFiles := openDesiredFiles(execdir, requestedRuns, "-"+strings.ReplaceAll(ep.Destination, ":", "_")+".i",
+		".x"+strconv.Itoa(msgSize)+".csv")
+
+	valuesMap := make(map[int]plotter.Values)
+
+	for _, f := range openFiles {
+		records, _ := csv.NewReader(f).ReadAll()
+		phaseColumn := headerColumn(records, "phase")
+		uplinkColumn := headerColumn(records, "uplink")
+		residenceColumn := headerColumn(records, "server-residence")
+		downlinkColumn := headerColumn(records, "downlink")
+		streamColumn := headerColumn(records, "stream")
+		idColumn := headerColumn(records, "id")
+		// Without the server timestamps in the results, the server logs are merged with them if available
+		merged := uplinkColumn == -1 || residenceColumn == -1 || downlinkColumn == -1
+		if merged && serverLogTimes == nil {
+			continue
+		}
+		for i, row := range records {
+			if i == 0 || isExcludedRecord(row, phaseColumn, flaggedIncluded) {
+				continue
+			}
+			rtt, fail := strconv.ParseFloat(row[2], 64)
+			if fail != nil {
+				continue
+			}
+			var uplink, residence, downlink float64
+			if merged {
+				var logged bool
+				uplink, residence, downlink, logged = serverLogDecomposition(row, rtt, streamColumn, idColumn)
+				if !logged {
+					continue
+				}
+			} else {
+				if uplink, fail = strconv.ParseFloat(row[uplinkColumn], 64); fail != nil {
+					continue
+				}
+				residence, _ = strconv.ParseFloat(row[residenceColumn], 64)
+				downlink, _ = strconv.ParseFloat(row[downlinkColumn], 64)
+			}
+			valuesMap[0] = append(valuesMap[0], uplink)
+			valuesMap[1] = append(valuesMap[1], residence)
+			valuesMap[2] = append(valuesMap[2], downlink)
+			valuesMap[3] = append(valuesMap[3], rtt-residence)
+		}
+	}
+
+	closeOpenFiles(openFiles)
+
+	p.Y.Label.Text = "Time (ms)"
+	p.Y.Tick.Marker = hplot.Ticks{N: AxisTicks}
+	p.Title.Text = ep.Description + " - " + strconv.Itoa(msgSize) + "B"
+	configurePlotFontSizesMultiple(p, true)
+
+	return generateIntBoxPlotAndLimits(p, &valuesMap, func(series int) string {
+		return decompositionSeries[series]
+	}, percentilesToRemove, whiskerMin, whiskerMax)
+}
+
+// Plot the rtt of the multiplexed streams against the one of the separate connections, for every endpoint and size
+func streamsBoxPlots(settings Settings, wg *sync.WaitGroup) {
+	rows := len(settings.Endpoints)
+	cols := len(settings.MsgSizes)
+	min := math.Inf(1)
+	max := math.Inf(-1)
+	plots := make([][]*plot.Plot, rows)
+	for i := 0; i < rows; i++ {
+		plots[i] = make([]*plot.Plot, cols)
+		for j := 0; j < cols; j++ {
+			var tmpMin, tmpMax float64
+			plots[i][j], tmpMin, tmpMax = streamsBoxPlot(settings.Endpoints[i], settings.Streams, settings.MsgSizes[j],
+				settings.ExecDir, settings.PercentilesToRemove, settings.WhiskerMin, settings.WhiskerMax,
+				requestedSlice(settings))
+			min = floats.Min([]float64{min, tmpMin})
+			max = floats.Max([]float64{max, tmpMax})
+		}
+	}
+
+	if settings.RttMin != 0 {
+		min = settings.RttMin
+	}
+	if settings.RttMax != 0 {
+		max = settings.RttMax
+	}
+	if !settings.EqualizationDisabled {
+		adjustMinMaxY(plots, rows, cols, min, max)
+	}
+	commonPlotting(plots, rows, cols, 100+cols*len(streamsModes)*200,
+		settings.ExecDir+PlotDirName+"streamsBoxPlot")
+
+	wg.Done()
+}
+
+// Return a boxplot of the e2e rtt of the streams multiplexed over a single connection and of the same streams on
+// separate connections given the endpoint and the size
+func streamsBoxPlot(ep EndpointData,
+	streams int,
+	msgSize int,
+	execdir string,
+	percentilesToRemove int,
+	whiskerMin int,
+	whiskerMax int,
+	requestedRuns []int) (*plot.Plot, float64, float64) {
+	log.Println(LoggerHdr + "Streams plot for " + ep.Description + " and message size " + strconv.Itoa(msgSize))
+	p, err := plot.New()
+	errMgmt(err)
+
+	valuesMap := make(map[int]plotter.Values)
+	background := false
+
+	for mode, name := range streamsModes {
+		// Open the desired files
+		openFiles := openDesiredFiles(execdir, requestedRuns, "-streams-"+name+"-"+
+			strings.ReplaceAll(ep.Destination, ":", "_")+".x"+strconv.Itoa(msgSize)+".csv")
+		for _, f := range openFiles {
+			records, _ := csv.NewReader(f).ReadAll()
+			// With per-stream sizes only the stream carrying the message size of the step is plotted, the other
+			// ones are the background streams blocking it
+			sizeColumn := headerColumn(records, "request-size")
+			if sizeColumn != -1 {
+				background = true
+			}
+			for i, row := range records {
+				if i != 0 {
+					if sizeColumn != -1 && (len(row) <= sizeColumn || row[sizeColumn] != strconv.Itoa(msgSize)) {
+						continue
+					}
+					parsed, fail := strconv.ParseFloat(row[2], 64)
+					if fail != nil || parsed < 0 {
+						continue
+					}
+					valuesMap[mode] = append(valuesMap[mode], parsed)
+				}
+			}
+		}
+		closeOpenFiles(openFiles)
+	}
+
+	p.Y.Label.Text = "E2E RTT (ms)"
+	p.Y.Tick.Marker = hplot.Ticks{N: AxisTicks}
+	p.Title.Text = ep.Description + " - " + strconv.Itoa(msgSize) + "B - " + strconv.Itoa(streams) + " streams"
+	if background {
+		p.Title.Text += " (with background streams)"
+	}
+	configurePlotFontSizesMultiple(p, true)
+
+	return generateIntBoxPlotAndLimits(p, &valuesMap, func(mode int) string {
+		if streamsModes[mode] == "mux" {
+			return "Single connection"
+		}
+		return "Separate connections"
+	}, percentilesToRemove, whiskerMin, whiskerMax)
+}
+
+// Plot the rtt of the messages depending on their position inside the burst, for every endpoint, bursty profile and size
+func burstPositionBoxPlots(settings Settings, wg *sync.WaitGroup) {
+	var profiles []IntervalData
+	for _, inter := range settings.Intervals {
+		if inter.IsBursty() {
+			profiles = append(profiles, inter)
+		}
+	}
+	rows := len(settings.Endpoints) * len(profiles)
+	cols := len(settings.MsgSizes)
+	min := math.Inf(1)
+	max := math.Inf(-1)
+	plots := make([][]*plot.Plot, rows)
+	for i := 0; i < rows; i++ {
+		plots[i] = make([]*plot.Plot, cols)
+		for j := 0; j < cols; j++ {
+			var tmpMin, tmpMax float64
+			plots[i][j], tmpMin, tmpMax = burstPositionBoxPlot(settings.Endpoints[i/len(profiles)],
+				profiles[i%len(profiles)], settings.MsgSizes[j], settings.ExecDir, settings.PercentilesToRemove,
+				settings.WhiskerMin, settings.WhiskerMax, requestedSlice(settings), settings.FlaggedIncluded)
+			min = floats.Min([]float64{min, tmpMin})
+			max = floats.Max([]float64{max, tmpMax})
+		}
+	}
+
+	if settings.RttMin != 0 {
+		min = settings.RttMin
+	}
+	if settings.RttMax != 0 {
+		max = settings.RttMax
+	}
+	if !settings.EqualizationDisabled {
+		adjustMinMaxY(plots, rows, cols, min, max)
+	}
+	commonPlotting(plots, rows, cols, 100+cols*1000, settings.ExecDir+PlotDirName+"burstPositionBoxPlot")
+
+	wg.Done()
+}
+
+// Return a boxplot of the e2e rtt of each position inside the bursts given the endpoint, the profile and the size
+func burstPositionBoxPlot(ep EndpointData,
+	profile IntervalData,
+	msgSize int,
+	execdir string,
+	percentilesToRemove int,
+	whiskerMin int,
+	whiskerMax int,
+	requestedRuns []int,
+	flaggedIncluded bool) (*plot.Plot, float64, float64) {
+	log.Println(LoggerHdr + "Burst plot for " + ep.Description + ", profile " + profile.String() +
+		" and message size " + strconv.Itoa(msgSize))
+	p, err := plot.New()
+	errMgmt(err)
+
+	// Open the desired files
+	openFiles := openDesiredFiles(execdir, requestedRuns,
+		"-"+strings.ReplaceAll(ep.Destination, ":", "_")+".i"+profile.Label()+".x"+strconv.Itoa(msgSize)+".csv")
+
+	valuesMap := make(map[int]plotter.Values)
+
+	for _, f := range openFiles {
+		records, _ := csv.NewReader(f).ReadAll()
+		phaseColumn := headerColumn(records, "phase")
+		positionColumn := headerColumn(records, "burst-position")
+		if positionColumn == -1 {
+			continue
+		}
+		for i, row := range records {
+			if i != 0 && !isExcludedRecord(row, phaseColumn, flaggedIncluded) {
+				parsed, fail := strconv.ParseFloat(row[2], 64)
+				if fail != nil {
+					continue
+				}
+				parsed = correctedRtt(parsed, f.Name())
+				position, fail := strconv.Atoi(row[positionColumn])
+				if fail != nil {
+					continue
+				}
+				valuesMap[position+1] = append(valuesMap[position+1], parsed)
+			}
+		}
+	}
+
+	closeOpenFiles(openFiles)
+
+	p.X.Label.Text = "Position in the Burst"
+	p.Y.Label.Text = "E2E RTT (ms)"
+	p.Y.Tick.Marker = hplot.Ticks{N: AxisTicks}
+	p.Title.Text = ep.Description + " - " + profile.Title() + " - " + strconv.Itoa(msgSize) + "B"
+	configurePlotFontSizesMultiple(p, true)
+
+	return generateIntBoxPlotAndLimits(p, &valuesMap, strconv.Itoa, percentilesToRemove, whiskerMin, whiskerMax)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/csv"
+	"go-hep.org/x/hep/hplot"
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Prefix of the files storing the baseline rtt measured by the calibration towards the loopback server
+const CalibrationPrefix = "calibration"
+
+// Median baseline rtt of each interval and size, subtracted from the rtt samples if requested
+var calibrationBaselines map[string]float64
+
+// Return the name of the calibration file of the interval and the size
+func calibrationFilename(execdir string, si IntervalData, msgSize int) string {
+	return execdir + DataDirName + CalibrationPrefix + ".i" + si.Label() + ".x" + strconv.Itoa(msgSize) + ".csv"
+}
+
+// Return the key of the baselines map of the rtt file, which is its interval and size suffix
+func calibrationKey(filename string) string {
+	filename = filenameOnly(filename)
+	index := strings.LastIndex(filename, ".i")
+	if index == -1 {
+		return ""
+	}
+	return strings.TrimSuffix(filename[index:], ".csv")
+}
+
+// Return the steady rtt samples of the calibration of the interval and size, nil if it is missing
+func calibrationValues(execdir string, si IntervalData, msgSize int) plotter.Values {
+	f, err := openDataFile(calibrationFilename(execdir, si, msgSize))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	records, _ := csv.NewReader(f).ReadAll()
+	phaseColumn := headerColumn(records, "phase")
+	var values plotter.Values
+	for i, row := range records {
+		if i != 0 && !isExcludedRecord(row, phaseColumn, false) {
+			parsed, fail := strconv.ParseFloat(row[2], 64)
+			if fail != nil || parsed < 0 {
+				continue
+			}
+			values = append(values, parsed)
+		}
+	}
+	return values
+}
+
+// True if the calibration has been run in the campaign folder
+func calibrationAvailable(settings Settings) bool {
+	for _, inter := range settings.Intervals {
+		for _, size := range settings.MsgSizes {
+			if f, err := openDataFile(calibrationFilename(settings.ExecDir, inter, size)); err == nil {
+				f.Close()
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Load the median baseline rtt of every interval and size, in order to subtract it from the samples
+func loadCalibrationBaselines(settings Settings) {
+	calibrationBaselines = make(map[string]float64)
+	for _, inter := range settings.Intervals {
+		for _, size := range settings.MsgSizes {
+			values := calibrationValues(settings.ExecDir, inter, size)
+			if len(values) == 0 {
+				log.Println(LoggerHdr + "WARNING: no calibration for interval " + inter.String() + " and size " +
+					strconv.Itoa(size) + ", its samples are not corrected")
+				continue
+			}
+			sort.Float64s(values)
+			calibrationBaselines[".i"+inter.Label()+".x"+strconv.Itoa(size)] = values[len(values)/2]
+		}
+	}
+}
+
+// Return the rtt sample of the file without the baseline of its interval and size, if the subtraction is requested.
+// The samples of the messages sent after a connection reset are not corrected
+func correctedRtt(rtt float64, filename string) float64 {
+	if rtt < 0 {
+		return rtt
+	}
+	return rtt - calibrationBaselines[calibrationKey(filename)]
+}
+
+// Plot the baseline rtt measured towards the loopback server, for every interval
+func calibrationBoxPlots(settings Settings, wg *sync.WaitGroup) {
+	rows := 1
+	cols := len(settings.Intervals)
+	min := math.Inf(1)
+	max := math.Inf(-1)
+	plots := make([][]*plot.Plot, rows)
+	plots[0] = make([]*plot.Plot, cols)
+	for j := 0; j < cols; j++ {
+		var tmpMin, tmpMax float64
+		plots[0][j], tmpMin, tmpMax = calibrationBoxPlot(settings.Intervals[j], settings.MsgSizes, settings.ExecDir,
+			settings.PercentilesToRemove, settings.WhiskerMin, settings.WhiskerMax)
+		min = floats.Min([]float64{min, tmpMin})
+		max = floats.Max([]float64{max, tmpMax})
+	}
+
+	if !settings.EqualizationDisabled {
+		adjustMinMaxY(plots, rows, cols, min, max)
+	}
+	commonPlotting(plots, rows, cols, 100+cols*len(settings.MsgSizes)*200,
+		settings.ExecDir+PlotDirName+"calibrationBoxPlot")
+
+	wg.Done()
+}
+
+// Return a boxplot of the baseline rtt of the sizes given the interval
+func calibrationBoxPlot(si IntervalData,
+	msgSizes []int,
+	execdir string,
+	percentilesToRemove int,
+	whiskerMin int,
+	whiskerMax int) (*plot.Plot, float64, float64) {
+	log.Println(LoggerHdr + "Calibration plot for send interval " + si.String())
+	p, err := plot.New()
+	errMgmt(err)
+
+	valuesMap := make(map[int]plotter.Values)
+	for _, size := range msgSizes {
+		if values := calibrationValues(execdir, si, size); len(values) > 0 {
+			valuesMap[size] = values
+		}
+	}
+
+	p.X.Label.Text = "Request Size (B)"
+	p.Y.Label.Text = "Baseline E2E RTT (ms)"
+	p.Y.Tick.Marker = hplot.Ticks{N: AxisTicks}
+	p.Title.Text = "Loopback - " + si.Title()
+	configurePlotFontSizesMultiple(p, true)
+
+	return generateIntBoxPlotAndLimits(p, &valuesMap, strconv.Itoa, percentilesToRemove, whiskerMin, whiskerMax)
+}
@@ -25,15 +25,15 @@ func typedCDFs(settings Settings, objectType int, wg *sync.WaitGroup) {
 			switch objectType {
 			case ENDPOINTS:
 				plots[i][j] = intXsizeCDF(settings.MsgSizes[i], settings.Intervals[j], settings.Endpoints, settings.ExecDir,
-					settings.PercentilesToRemove, requestedSlice(settings))
+					settings.PercentilesToRemove, requestedSlice(settings), settings.FlaggedIncluded)
 				filename = "endpointsCDF"
 			case INTERVALS:
 				plots[i][j] = sizeXepCDF(settings.Endpoints[i], settings.MsgSizes[j], settings.Intervals, settings.ExecDir,
-					settings.PercentilesToRemove, requestedSlice(settings))
+					settings.PercentilesToRemove, requestedSlice(settings), settings.FlaggedIncluded)
 				filename = "intervalsCDF"
 			case SIZES:
 				plots[i][j] = intXepCDF(settings.Endpoints[i], settings.Intervals[j], settings.MsgSizes, settings.ExecDir,
-					settings.PercentilesToRemove, requestedSlice(settings))
+					settings.PercentilesToRemove, requestedSlice(settings), settings.FlaggedIncluded)
 				filename = "sizesCDF"
 			default:
 				panic("Wrong objectType in loop elements: only values 0,1 and 2 are allowed")
@@ -61,34 +61,40 @@ func typedCDFs(settings Settings, objectType int, wg *sync.WaitGroup) {
 // Return a cdf of the e2e rtt of the sizes given the interval and the endpoint
 func intXepCDF(
 	ep EndpointData,
-	si int,
+	si IntervalData,
 	sizes []int,
 	execdir string,
 	percentilesToRemove int,
-	requestedRuns []int) *plot.Plot {
-	log.Println(LoggerHdr + "CDF for " + ep.Description + " and send interval " + strconv.Itoa(si))
+	requestedRuns []int,
+	flaggedIncluded bool) *plot.Plot {
+	log.Println(LoggerHdr + "CDF for " + ep.Description + " and send interval " + si.String())
 	p, err := plot.New()
 	errMgmt(err)
 
 	// Open the desired files
 	openFiles := openDesiredFiles(execdir, requestedRuns,
-		"-"+strings.ReplaceAll(ep.Destination, ":", "_")+".i"+strconv.Itoa(si)+".x")
+		"-"+strings.ReplaceAll(ep.Destination, ":", "_")+".i"+si.Label()+".x")
 
 	valuesMap := make(map[int]plotter.Values)
 
 	for _, f := range openFiles {
 		filename := filenameOnly(f.Name())
+		if !isRttFile(filename) {
+			continue
+		}
 		parsedSizeVal, err := strconv.ParseInt(filename[strings.LastIndex(filename, "x")+1:len(filename)-4], 10, 32)
 		sizeVal := int(parsedSizeVal)
 		errMgmt(err)
 		if intInSlice(sizeVal, sizes) {
 			records, _ := csv.NewReader(f).ReadAll()
+			phaseColumn := headerColumn(records, "phase")
 			for i, row := range records {
-				if i != 0 {
+				if i != 0 && !isExcludedRecord(row, phaseColumn, flaggedIncluded) {
 					parsed, fail := strconv.ParseFloat(row[2], 64)
 					if fail != nil {
 						continue
 					}
+					parsed = correctedRtt(parsed, filename)
 					valuesMap[sizeVal] = append(valuesMap[sizeVal], parsed)
 				}
 			}
@@ -100,10 +106,10 @@ func intXepCDF(
 	p.X.Label.Text = "E2E RTT (ms)"
 	p.Y.Label.Text = "P(x)"
 	p.X.Tick.Marker = hplot.Ticks{N: AxisTicks}
-	p.Title.Text = ep.Description + " - " + strconv.Itoa(si) + "ms"
+	p.Title.Text = ep.Description + " - " + si.Title()
 	configurePlotFontSizesMultiple(p, false)
 
-	generateIntCDFPlot(p, &valuesMap, percentilesToRemove)
+	generateIntCDFPlot(p, &valuesMap, strconv.Itoa, percentilesToRemove)
 
 	return p
 }
@@ -112,10 +118,11 @@ func intXepCDF(
 func sizeXepCDF(
 	ep EndpointData,
 	msgSize int,
-	sis []int,
+	sis []IntervalData,
 	execdir string,
 	percentilesToRemove int,
-	requestedRuns []int) *plot.Plot {
+	requestedRuns []int,
+	flaggedIncluded bool) *plot.Plot {
 	log.Println(LoggerHdr + "CDF for " + ep.Description + " and message size " + strconv.Itoa(msgSize))
 	p, err := plot.New()
 	errMgmt(err)
@@ -128,18 +135,17 @@ func sizeXepCDF(
 
 	for _, f := range openFiles {
 		filename := filenameOnly(f.Name())
-		parsedInterVal, err := strconv.ParseInt(
-			filename[strings.LastIndex(filename, ".i")+2:strings.LastIndex(filename, ".x")], 10, 32)
-		interVal := int(parsedInterVal)
-		errMgmt(err)
-		if intInSlice(interVal, sis) {
+		interVal := intervalIndex(filename[strings.LastIndex(filename, ".i")+2:strings.LastIndex(filename, ".x")], sis)
+		if interVal != -1 {
 			records, _ := csv.NewReader(f).ReadAll()
+			phaseColumn := headerColumn(records, "phase")
 			for i, row := range records {
-				if i != 0 {
+				if i != 0 && !isExcludedRecord(row, phaseColumn, flaggedIncluded) {
 					parsed, fail := strconv.ParseFloat(row[2], 64)
 					if fail != nil {
 						continue
 					}
+					parsed = correctedRtt(parsed, filename)
 					valuesMap[interVal] = append(valuesMap[interVal], parsed)
 				}
 			}
@@ -154,7 +160,7 @@ func sizeXepCDF(
 	p.Title.Text = ep.Description + " - " + strconv.Itoa(msgSize) + "B"
 	configurePlotFontSizesMultiple(p, false)
 
-	generateIntCDFPlot(p, &valuesMap, percentilesToRemove)
+	generateIntCDFPlot(p, &valuesMap, func(k int) string { return sis[k].String() }, percentilesToRemove)
 
 	return p
 }
@@ -162,16 +168,17 @@ func sizeXepCDF(
 // Return a cdf of the e2e rtt of the endpoints given the interval and the size
 func intXsizeCDF(
 	msgSize int,
-	si int, eps []EndpointData,
+	si IntervalData, eps []EndpointData,
 	execdir string,
 	percentilesToRemove int,
-	requestedRuns []int) *plot.Plot {
-	log.Println(LoggerHdr + "CDF for interval " + strconv.Itoa(si) + " and message size " + strconv.Itoa(msgSize))
+	requestedRuns []int,
+	flaggedIncluded bool) *plot.Plot {
+	log.Println(LoggerHdr + "CDF for interval " + si.String() + " and message size " + strconv.Itoa(msgSize))
 	p, err := plot.New()
 	errMgmt(err)
 
 	// Open the desired files
-	openFiles := openDesiredFiles(execdir, requestedRuns, ".i"+strconv.Itoa(si)+".x"+strconv.Itoa(msgSize)+".csv")
+	openFiles := openDesiredFiles(execdir, requestedRuns, ".i"+si.Label()+".x"+strconv.Itoa(msgSize)+".csv")
 
 	valuesMap := make(map[string]plotter.Values)
 
@@ -181,12 +188,14 @@ func intXsizeCDF(
 		description, present := nameFromDest(parsedSizeVal, &eps)
 		if present {
 			records, _ := csv.NewReader(f).ReadAll()
+			phaseColumn := headerColumn(records, "phase")
 			for i, row := range records {
-				if i != 0 {
+				if i != 0 && !isExcludedRecord(row, phaseColumn, flaggedIncluded) {
 					parsed, fail := strconv.ParseFloat(row[2], 64)
 					if fail != nil {
 						continue
 					}
+					parsed = correctedRtt(parsed, filename)
 					valuesMap[description] = append(valuesMap[description], parsed)
 				}
 			}
@@ -198,7 +207,7 @@ func intXsizeCDF(
 	p.X.Label.Text = "E2E RTT (ms)"
 	p.Y.Label.Text = "P(x)"
 	p.X.Tick.Marker = hplot.Ticks{N: AxisTicks}
-	p.Title.Text = strconv.Itoa(si) + "ms - " + strconv.Itoa(msgSize) + "B"
+	p.Title.Text = si.Title() + " - " + strconv.Itoa(msgSize) + "B"
 	configurePlotFontSizesMultiple(p, false)
 
 	generateStringCDFPlot(p, &valuesMap, percentilesToRemove)
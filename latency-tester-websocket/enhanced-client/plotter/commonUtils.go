@@ -62,6 +62,50 @@ func nameFromDest(dest string, eps *[]EndpointData) (string, bool) {
 	return "", false
 }
 
+func (i *IntervalData) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&i.Millis); err == nil {
+		return nil
+	}
+	i.Millis = 0
+	return unmarshal(&i.Profile)
+}
+
+// Value as written in the settings file
+func (i IntervalData) String() string {
+	if i.Profile == "" {
+		return strconv.Itoa(i.Millis)
+	}
+	return i.Profile
+}
+
+// Value used in the file names
+func (i IntervalData) Label() string {
+	return strings.NewReplacer(":", "_", "/", "_").Replace(i.String())
+}
+
+// Value used in the plot titles
+func (i IntervalData) Title() string {
+	if i.Profile == "" {
+		return strconv.Itoa(i.Millis) + "ms"
+	}
+	return i.Profile
+}
+
+// True if the profile sends the messages in bursts
+func (i IntervalData) IsBursty() bool {
+	return strings.HasPrefix(i.Profile, "burst:") || strings.HasPrefix(i.Profile, "onoff:")
+}
+
+// Return the index of the interval with the given file name label, -1 if missing
+func intervalIndex(label string, list []IntervalData) int {
+	for i, b := range list {
+		if b.Label() == label {
+			return i
+		}
+	}
+	return -1
+}
+
 // True if the int value is in the slice
 func intInSlice(a int, list []int) bool {
 	for _, b := range list {
@@ -72,6 +116,16 @@ func intInSlice(a int, list []int) bool {
 	return false
 }
 
+// True if the string value is in the slice
+func stringInSlice(a string, list []string) bool {
+	for _, b := range list {
+		if b == a {
+			return true
+		}
+	}
+	return false
+}
+
 // Return the Y axis values for the CDF graph
 func yValsCDF(length int) []float64 {
 	var toReturn []float64
@@ -107,20 +161,27 @@ func adjustMinMaxX(plots [][]*plot.Plot, rows, cols int, min, max float64) {
 	}
 }
 
-// Open the files with the name containing the nameLike strings
-func openDesiredFiles(execdir string, requestedRuns []int, nameLike ...string) []*os.File {
+// Open the files with the name containing the nameLike strings, reading the rotated ones as a single file
+func openDesiredFiles(execdir string, requestedRuns []int, nameLike ...string) []*dataFile {
 	files, err := ioutil.ReadDir(execdir + DataDirName)
 	errMgmt(err)
-	var openFiles []*os.File
+	var openFiles []*dataFile
 	for _, f := range files {
-		filename := filenameOnly(f.Name())
+		name := f.Name()
+		if isSegmentFile(name) {
+			continue
+		}
+		if strings.HasSuffix(name, SegmentsIndexSuffix) {
+			name = strings.TrimSuffix(name, SegmentsIndexSuffix) + ".csv"
+		}
+		filename := filenameOnly(name)
 		if strings.Contains(filename, nameLike[0]) {
 			fileRun, _ := strconv.Atoi(strings.Split(filename, "-")[0])
 			// It can contain one or two strings, so it checks if the second value is present and then if it is in the name
 			if len(nameLike) > 1 && !strings.Contains(filename, nameLike[1]) || !intInSlice(fileRun, requestedRuns) {
 				continue
 			}
-			file, err := os.Open(execdir + DataDirName + f.Name())
+			file, err := openDataFile(execdir + DataDirName + name)
 			errMgmt(err)
 			openFiles = append(openFiles, file)
 		}
@@ -128,7 +189,7 @@ func openDesiredFiles(execdir string, requestedRuns []int, nameLike ...string) [
 	return openFiles
 }
 
-func closeOpenFiles(files []*os.File) {
+func closeOpenFiles(files []*dataFile) {
 	for _, f := range files {
 		f.Close()
 	}
@@ -137,6 +198,7 @@ func closeOpenFiles(files []*os.File) {
 // Return a BoxPlot graph and its min and max values with int as key of the map
 func generateIntBoxPlotAndLimits(p *plot.Plot,
 	valuesMap *map[int]plotter.Values,
+	keyName func(int) string,
 	percentilesToRemove int,
 	whiskerMin int,
 	whiskerMax int) (*plot.Plot, float64, float64) {
@@ -159,7 +221,7 @@ func generateIntBoxPlotAndLimits(p *plot.Plot,
 		boxplot, err := plotter.NewBoxPlot(w, position, (*valuesMap)[k])
 		errMgmt(err)
 		handleWhiskerAndOutliers(boxplot, whiskerMin, whiskerMax, (*valuesMap)[k], toRemove)
-		nominals = append(nominals, strconv.Itoa(k)+" (Median:"+strconv.FormatFloat(boxplot.Median, 'f', 2, 64)+")")
+		nominals = append(nominals, keyName(k)+" (Median:"+strconv.FormatFloat(boxplot.Median, 'f', 2, 64)+")")
 		mins = append(mins, (*valuesMap)[k][toRemove*percentilesToRemove])
 		maxes = append(maxes, (*valuesMap)[k][len((*valuesMap)[k])-toRemove*percentilesToRemove-1])
 		position += 1
@@ -215,7 +277,10 @@ func generateStringBoxPlotAndLimits(p *plot.Plot,
 }
 
 // Return a CDF graph with int as keys of the map
-func generateIntCDFPlot(p *plot.Plot, valuesMap *map[int]plotter.Values, percentilesToRemove int) {
+func generateIntCDFPlot(p *plot.Plot,
+	valuesMap *map[int]plotter.Values,
+	keyName func(int) string,
+	percentilesToRemove int) {
 	// Get map ordered keys
 	keys := make([]int, 0, len(*valuesMap))
 	for k := range *valuesMap {
@@ -236,7 +301,7 @@ func generateIntCDFPlot(p *plot.Plot, valuesMap *map[int]plotter.Values, percent
 		for i, y := range yValsCDF(len((*valuesMap)[k])) {
 			toAdd = append(toAdd, plotter.XY{X: (*valuesMap)[k][i], Y: y})
 		}
-		lines = append(lines, strconv.Itoa(k))
+		lines = append(lines, keyName(k))
 		lines = append(lines, toAdd)
 	}
 	err := plotutil.AddLines(p, lines...)
@@ -297,7 +362,10 @@ func getTcpPlotTitle(endpoints, intervals, sizes []string, streamCounter int) st
 		for _, inter := range intervals {
 			for _, size := range sizes {
 				if tracker == streamCounter {
-					return "TCP ACK Latency: " + addr + " - " + inter + "ms - " + size + "B"
+					if _, err := strconv.Atoi(inter); err == nil {
+						inter += "ms"
+					}
+					return "TCP ACK Latency: " + addr + " - " + inter + " - " + size + "B"
 				}
 				tracker += 1
 				if tracker > streamCounter {
@@ -334,6 +402,49 @@ func rttValues(pairs plotter.XYs) []float64 {
 	return rtts
 }
 
+// Return the index of the named column in the csv header, -1 if the column is missing
+func headerColumn(records [][]string, name string) int {
+	if len(records) == 0 {
+		return -1
+	}
+	for i, column := range records[0] {
+		if strings.TrimPrefix(column, "#") == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// True if the record has been flagged as warm-up or cool-down by the client and it must not be plotted
+func isExcludedRecord(row []string, phaseColumn int, flaggedIncluded bool) bool {
+	return !flaggedIncluded && phaseColumn != -1 && phaseColumn < len(row) && row[phaseColumn] != SteadyPhase
+}
+
+// Return the values of net.ipv4.tcp_slow_start_after_idle the idle-gap files were generated with
+func idleSlowStartValues(execdir string) []string {
+	files, err := ioutil.ReadDir(execdir + DataDirName)
+	errMgmt(err)
+	var values []string
+	for _, f := range files {
+		if !strings.Contains(f.Name(), "-idle-ss") || !strings.HasSuffix(f.Name(), "_idle-gaps.csv") {
+			continue
+		}
+		value := f.Name()[strings.Index(f.Name(), "-idle-ss")+8:]
+		value = value[:strings.Index(value, "-")]
+		if !stringInSlice(value, values) {
+			values = append(values, value)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+// True if the file contains the rtt of all the messages, not one of the additional series stored next to it
+func isRttFile(filename string) bool {
+	_, err := strconv.Atoi(strings.TrimSuffix(filename[strings.LastIndex(filename, ".x")+2:], ".csv"))
+	return err == nil
+}
+
 func filenameOnly(f string) string {
 	return f[strings.LastIndex(f, "/")+1:]
 }
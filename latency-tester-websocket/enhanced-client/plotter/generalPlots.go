@@ -285,12 +285,16 @@ func RttPlotter(settings Settings, wg *sync.WaitGroup) {
 				var absoluteFirst float64
 				var lastOfRun float64
 				var runTime string
+				var load hostLoad
+				instances := make(instanceSamples)
 				for runIndex, run := range requestedRuns {
-					file, err := os.Open(settings.ExecDir + DataDirName + strconv.Itoa(run) + "-" +
-						strings.ReplaceAll(addr.Destination, ":", "_") + ".i" + strconv.Itoa(inter) + ".x" +
+					file, err := openDataFile(settings.ExecDir + DataDirName + strconv.Itoa(run) + "-" +
+						strings.ReplaceAll(addr.Destination, ":", "_") + ".i" + inter.Label() + ".x" +
 						strconv.Itoa(size) + ".csv")
 					if err == nil {
 						records, _ := csv.NewReader(file).ReadAll()
+						phaseColumn := headerColumn(records, "phase")
+						instanceColumn := headerColumn(records, "server-instance")
 						var runGap float64
 						for i, row := range records {
 							if i != 0 {
@@ -298,6 +302,7 @@ func RttPlotter(settings Settings, wg *sync.WaitGroup) {
 								if fail != nil {
 									continue
 								}
+								parsed = correctedRtt(parsed, file.Name())
 								timeInter, fail := strconv.ParseFloat(row[0], 64)
 								if fail != nil {
 									continue
@@ -315,9 +320,19 @@ func RttPlotter(settings Settings, wg *sync.WaitGroup) {
 								}
 								// Convert values to ms
 								xValue := (timeInter - absoluteFirst - runGap) / 1000000000
-								values = append(values, plotter.XY{X: xValue, Y: parsed})
-								hourlyMap[runTime] = append(hourlyMap[runTime], parsed)
+								if !isExcludedRecord(row, phaseColumn, settings.FlaggedIncluded) {
+									values = append(values, plotter.XY{X: xValue, Y: parsed})
+									hourlyMap[runTime] = append(hourlyMap[runTime], parsed)
+									if instanceColumn != -1 {
+										instances.add(row[instanceColumn], plotter.XY{X: xValue, Y: parsed})
+									}
+								}
 								if i == len(records)-1 {
+									first, _ := strconv.ParseInt(records[1][0], 10, 64)
+									last, _ := strconv.ParseInt(row[0], 10, 64)
+									load.addStep(settings.ExecDir, run, file.Name(), first, last, func(ts int64) float64 {
+										return (float64(ts) - absoluteFirst - runGap) / 1000000000
+									})
 									lastOfRun = timeInter - runGap
 									// Save X of last record of the run, to divide all with a vertical line in the plot
 									if (runIndex + 1) != len(requestedRuns) {
@@ -336,7 +351,7 @@ func RttPlotter(settings Settings, wg *sync.WaitGroup) {
 						box.X.Label.Text = "UTC Time (hh:mm)"
 						box.Y.Label.Text = "E2E RTT (ms)"
 						box.Y.Tick.Marker = hplot.Ticks{N: AxisTicks}
-						box.Title.Text = "E2E Latency: " + addr.Description + " - " + strconv.Itoa(inter) + "ms - " + strconv.Itoa(size) + "B"
+						box.Title.Text = "E2E Latency: " + addr.Description + " - " + inter.Title() + " - " + strconv.Itoa(size) + "B"
 						configurePlotFontSizes(box, true)
 						boxplot, hourMin, hourMax := generateStringBoxPlotAndLimits(
 							box, &hourlyMap, settings.PercentilesToRemove, settings.WhiskerMin, settings.WhiskerMax)
@@ -365,7 +380,7 @@ func RttPlotter(settings Settings, wg *sync.WaitGroup) {
 				p.Y.Label.Text = "E2E RTT (ms)"
 				p.Y.Tick.Marker = hplot.Ticks{N: AxisTicks}
 				p.X.Tick.Marker = hplot.Ticks{N: AxisTicks}
-				p.Title.Text = "E2E Latency: " + addr.Description + " - " + strconv.Itoa(inter) + "ms - " + strconv.Itoa(size) + "B"
+				p.Title.Text = "E2E Latency: " + addr.Description + " - " + inter.Title() + " - " + strconv.Itoa(size) + "B"
 				configurePlotFontSizes(p, false)
 				// Remove the last three percentiles
 				sort.Slice(values, func(i, j int) bool {
@@ -381,6 +396,9 @@ func RttPlotter(settings Settings, wg *sync.WaitGroup) {
 				for _, line := range runInterruptions {
 					p.Add(line)
 				}
+				if len(instances) > 0 {
+					addInstanceScatters(p, instances)
+				}
 				if settings.RttMin != 0 {
 					p.Y.Min = settings.RttMin
 				} else {
@@ -391,9 +409,13 @@ func RttPlotter(settings Settings, wg *sync.WaitGroup) {
 				} else {
 					p.Y.Max = max
 				}
-				p.Draw(draw.New(pdfToSave))
+				if load.isEmpty() {
+					p.Draw(draw.New(pdfToSave))
+				} else {
+					drawWithHostLoad(p, &load, draw.New(pdfToSave))
+				}
 				mean, stdDev := stat.MeanStdDev(rttValues(values), nil)
-				fmt.Fprintln(tabWriter, addr.Description+"\t"+strconv.Itoa(inter)+"\t"+strconv.Itoa(size)+"\t"+
+				fmt.Fprintln(tabWriter, addr.Description+"\t"+inter.String()+"\t"+strconv.Itoa(size)+"\t"+
 					strconv.FormatFloat(mean, 'f', 2, 64)+"\t"+strconv.FormatFloat(stdDev, 'f', 2, 64))
 			}
 		}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/csv"
+	"go-hep.org/x/hep/hplot"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Suffix of the host load statistics sampled by the client during a step
+const HostStatsSuffix = "_host-stats.csv"
+
+// Host load sampled during a step, as series whose X values are the ones of the E2E plot
+type hostLoad struct {
+	cpu     plotter.XYs
+	softirq plotter.XYs
+	rx      plotter.XYs
+	tx      plotter.XYs
+}
+
+func (h *hostLoad) isEmpty() bool {
+	return len(h.cpu) == 0
+}
+
+// Read the host statistics file, nil if it is missing
+func readHostStats(filename string) [][]string {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	records, _ := csv.NewReader(f).ReadAll()
+	return records
+}
+
+// Add the host load sampled between the first and the last timestamp of the step whose results file is given. The
+// samples of the client are used if present, otherwise the ones of the enhanced client during the whole run
+func (h *hostLoad) addStep(execdir string, run int, rttFilename string, first, last int64, toX func(int64) float64) {
+	records := readHostStats(strings.TrimSuffix(rttFilename, ".csv") + HostStatsSuffix)
+	if records == nil {
+		records = readHostStats(execdir + DataDirName + strconv.Itoa(run) + "-host-stats.csv")
+	}
+	cpuColumn := headerColumn(records, "cpu")
+	softirqColumn := headerColumn(records, "softirq")
+	rxColumn := headerColumn(records, "rx-bytes")
+	txColumn := headerColumn(records, "tx-bytes")
+	if cpuColumn == -1 || softirqColumn == -1 || rxColumn == -1 || txColumn == -1 {
+		return
+	}
+	var previous int64
+	for i, row := range records {
+		if i == 0 {
+			continue
+		}
+		ts, fail := strconv.ParseInt(row[0], 10, 64)
+		if fail != nil {
+			continue
+		}
+		// The throughput is computed on the time elapsed since the previous sample
+		elapsed := ts - previous
+		previous = ts
+		if i == 1 || ts < first || ts > last || elapsed <= 0 {
+			continue
+		}
+		cpu, _ := strconv.ParseFloat(row[cpuColumn], 64)
+		softirq, _ := strconv.ParseFloat(row[softirqColumn], 64)
+		rx, _ := strconv.ParseFloat(row[rxColumn], 64)
+		tx, _ := strconv.ParseFloat(row[txColumn], 64)
+		x := toX(ts)
+		h.cpu = append(h.cpu, plotter.XY{X: x, Y: cpu})
+		h.softirq = append(h.softirq, plotter.XY{X: x, Y: softirq})
+		// Bytes per nanosecond to Mbit/s
+		h.rx = append(h.rx, plotter.XY{X: x, Y: rx * 8 * 1000 / float64(elapsed)})
+		h.tx = append(h.tx, plotter.XY{X: x, Y: tx * 8 * 1000 / float64(elapsed)})
+	}
+}
+
+// Draw the E2E plot with the host load below it, aligning their time axes
+func drawWithHostLoad(p *plot.Plot, load *hostLoad, dc draw.Canvas) {
+	cpuPlot, err := plot.New()
+	errMgmt(err)
+	cpuPlot.X.Label.Text = "Time (s)"
+	cpuPlot.Y.Label.Text = "Host CPU (%)"
+	cpuPlot.Y.Tick.Marker = hplot.Ticks{N: 4}
+	cpuPlot.X.Tick.Marker = hplot.Ticks{N: AxisTicks}
+	configurePlotFontSizes(cpuPlot, false)
+	err = plotutil.AddLines(cpuPlot, "CPU", load.cpu, "Softirq", load.softirq)
+	errMgmt(err)
+	cpuPlot.Y.Min = 0
+	cpuPlot.Y.Max = 100
+
+	netPlot, err := plot.New()
+	errMgmt(err)
+	netPlot.X.Label.Text = "Time (s)"
+	netPlot.Y.Label.Text = "NIC (Mbit/s)"
+	netPlot.Y.Tick.Marker = hplot.Ticks{N: 4}
+	netPlot.X.Tick.Marker = hplot.Ticks{N: AxisTicks}
+	configurePlotFontSizes(netPlot, false)
+	err = plotutil.AddLines(netPlot, "RX", load.rx, "TX", load.tx)
+	errMgmt(err)
+	netPlot.Y.Min = 0
+	// An idle interface would make the axis degenerate
+	if netPlot.Y.Max <= 0 {
+		netPlot.Y.Max = 1
+	}
+
+	for _, panel := range []*plot.Plot{cpuPlot, netPlot} {
+		panel.X.Min = p.X.Min
+		panel.X.Max = p.X.Max
+		panel.Legend.Top = true
+	}
+
+	plots := [][]*plot.Plot{{p}, {cpuPlot}, {netPlot}}
+	canvases := plot.Align(plots, draw.Tiles{Rows: 3, Cols: 1}, dc)
+	// The E2E plot takes the upper 60% of the page, the host load panels 20% each
+	height := dc.Max.Y - dc.Min.Y
+	bounds := []vg.Length{1, 0.4, 0.2, 0}
+	for i := range plots {
+		canvases[i][0].Max.Y = dc.Min.Y + height*bounds[i]
+		canvases[i][0].Min.Y = dc.Min.Y + height*bounds[i+1]
+		plots[i][0].Draw(canvases[i][0])
+	}
+}
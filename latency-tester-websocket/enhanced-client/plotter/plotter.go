@@ -29,6 +29,12 @@ type EndpointData struct {
 	TlsEnabled  bool   `yaml:"tls_enabled"`
 }
 
+// Entry of the intervals sweep: a fixed send interval in milliseconds or a traffic profile such as "burst:5/100"
+type IntervalData struct {
+	Millis  int
+	Profile string
+}
+
 type Settings struct {
 	Runs                 int            `yaml:"runs"`
 	RunsInterval         int            `yaml:"runs_interval"`      // in minutes
@@ -37,7 +43,7 @@ type Settings struct {
 	PingDestinations     []PingData     `yaml:"ping_destinations"`
 	PingInterval         int            `yaml:"ping_interval"` // in seconds
 	Endpoints            []EndpointData `yaml:"endpoints"`
-	Intervals            []int          `yaml:"intervals"`     // in milliseconds
+	Intervals            []IntervalData `yaml:"intervals"`     // in milliseconds or as traffic profiles
 	MsgSizes             []int          `yaml:"msg_sizes"`     // in bytes
 	ResponseSize         int            `yaml:"response_size"` // in bytes
 	TcpdumpEnabled       bool           `yaml:"tcpdump_enabled"`
@@ -49,6 +55,13 @@ type Settings struct {
 	WhiskerMin           int            `yaml:"whisker_min"`
 	WhiskerMax           int            `yaml:"whisker_max"`
 	RunsToPlot           []int          `yaml:"runs_to_plot"`
+	FlaggedIncluded      bool           `yaml:"flagged_samples_included"`
+	IdleGaps             []int          `yaml:"idle_gaps"` // in milliseconds
+	ReconnectEvery       int            `yaml:"reconnect_every"`
+	Streams              int            `yaml:"streams"`
+	CalibrationSubtract  bool           `yaml:"calibration_subtracted"`
+	ServerTimestamps     bool           `yaml:"server_timestamps"`
+	ServerLogs           string         `yaml:"server_logs"` // folder of the message logs of the servers
 }
 
 const (
@@ -57,6 +70,18 @@ const (
 	SIZES     = iota
 )
 
+// Series of the cold connections files, in the order of their columns
+var coldConnectionSeries = []string{"TCP setup", "Total setup", "First message RTT"}
+
+// Series of the rtt decomposition, the network one being the e2e rtt minus the server residence
+var decompositionSeries = []string{"Uplink", "Server residence", "Downlink", "Network (E2E - residence)"}
+
+// Modes of the streams comparison, in the order they are plotted
+var streamsModes = []string{"mux", "conn"}
+
+// Phase of the samples that are neither warm-up nor cool-down ones
+const SteadyPhase = "steady"
+
 const AxisTicks = 15
 const PlotDirName = "plots/"
 const DataDirName = "raw-data/"
@@ -151,11 +176,30 @@ func main() {
 		"- pingPlot.pdf = Representation of the variation of the network-level round trip time throughout the execution" +
 		" of the enhanced client.\n" +
 		"- e2eLatency.pdf = The plotter puts together all the runs regarding each combination of the parameters and plots" +
-		" the round trip time variation throughout the execution of the enhanced client.\n" +
+		" the round trip time variation throughout the execution of the enhanced client, with the host load below it if" +
+		" it has been sampled and the samples coloured by server instance if it has been stored.\n" +
 		"- e2eLatencyPerRunBoxplot.pdf = A BoxPlot representation of the round trip time during each run of every" +
-		" combination of the parameters.")
+		" combination of the parameters.\n" +
+		"- idleGapsBoxPlot.pdf = The BoxPlot representation of the rtt of the first message after each idle gap, for" +
+		" each endpoint x slow start after idle value and message size combination (only if idle gaps are requested).\n" +
+		"- burstPositionBoxPlot.pdf = The BoxPlot representation of the rtt of each position inside the bursts, for each" +
+		" endpoint x bursty traffic profile and message size combination (only if bursty profiles are requested).\n" +
+		"- coldConnectionsBoxPlot.pdf = The BoxPlot representation of the setup times and of the first message rtt of" +
+		" the cold connections, for each endpoint and message size combination (only if reconnections are requested).\n" +
+		"- streamsBoxPlot.pdf = The BoxPlot representation of the rtt of the streams multiplexed over a single connection" +
+		" and of the same streams on separate connections, for each endpoint and message size combination (only if" +
+		" streams are requested).\n" +
+		"- calibrationBoxPlot.pdf = The BoxPlot representation of the baseline rtt measured towards the loopback server," +
+		" for each interval and message size combination (only if the calibration was run).\n" +
+		"- rttDecompositionBoxPlot.pdf = The BoxPlot representation of the uplink, server residence and downlink times" +
+		" of the messages, for each endpoint and message size combination (only if the server timestamps are" +
+		" requested or the server logs are available).")
 	readme.Close()
 
+	if settings.CalibrationSubtract {
+		loadCalibrationBaselines(settings)
+	}
+
 	var wg sync.WaitGroup
 
 	if settings.TcpdumpEnabled {
@@ -171,6 +215,36 @@ func main() {
 		wg.Add(1)
 		go PingPlotter(settings, &wg)
 	}
+	if len(settings.IdleGaps) > 0 {
+		wg.Add(1)
+		go idleGapBoxPlots(settings, &wg)
+	}
+	if settings.Streams > 1 {
+		wg.Add(1)
+		go streamsBoxPlots(settings, &wg)
+	}
+	if settings.ReconnectEvery > 0 {
+		wg.Add(1)
+		go coldConnectionBoxPlots(settings, &wg)
+	}
+	for _, inter := range settings.Intervals {
+		if inter.IsBursty() {
+			wg.Add(1)
+			go burstPositionBoxPlots(settings, &wg)
+			break
+		}
+	}
+	if settings.ServerLogs != "" {
+		loadServerLogs(settings.ServerLogs)
+	}
+	if settings.ServerTimestamps || settings.ServerLogs != "" {
+		wg.Add(1)
+		go rttDecompositionBoxPlots(settings, &wg)
+	}
+	if calibrationAvailable(settings) {
+		wg.Add(1)
+		go calibrationBoxPlots(settings, &wg)
+	}
 	wg.Add(7)
 	go typedBoxPlots(settings, SIZES, &wg)
 	go typedBoxPlots(settings, INTERVALS, &wg)
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Suffix of the index listing the segments of a results file rotated by the client
+const SegmentsIndexSuffix = "_segments.csv"
+
+// Infix of the names of the segments, followed by their number
+const SegmentInfix = "_segment-"
+
+// Results file read as a whole, even if the client rotated it into segments
+type dataFile struct {
+	name     string
+	segments []*os.File
+	io.Reader
+}
+
+func (f *dataFile) Name() string {
+	return f.name
+}
+
+func (f *dataFile) Close() error {
+	for _, segment := range f.segments {
+		segment.Close()
+	}
+	return nil
+}
+
+// True if the file is a segment of a rotated results file, read through its index
+func isSegmentFile(filename string) bool {
+	return strings.Contains(filename, SegmentInfix)
+}
+
+// Open the results file, or the concatenation of its segments if the client rotated it
+func openDataFile(filename string) (*dataFile, error) {
+	file, err := os.Open(filename)
+	if err == nil {
+		return &dataFile{name: filename, segments: []*os.File{file}, Reader: file}, nil
+	}
+	prefix := strings.TrimSuffix(filename, ".csv")
+	if _, indexErr := os.Stat(prefix + SegmentsIndexSuffix); indexErr != nil {
+		return nil, err
+	}
+	return openRotatedFile(filename, prefix)
+}
+
+// Open the segments listed in the index, verifying their checksums, plus the last one if it was not completed. The
+// header of the segments after the first one is skipped
+func openRotatedFile(filename, prefix string) (*dataFile, error) {
+	index, err := os.Open(prefix + SegmentsIndexSuffix)
+	if err != nil {
+		return nil, err
+	}
+	records, err := csv.NewReader(index).ReadAll()
+	index.Close()
+	if err != nil {
+		return nil, err
+	}
+	f := &dataFile{name: filename}
+	var readers []io.Reader
+	addSegment := func(segmentName string) error {
+		segment, err := os.Open(segmentName)
+		if err != nil {
+			return err
+		}
+		f.segments = append(f.segments, segment)
+		if len(readers) == 0 {
+			readers = append(readers, segment)
+			return nil
+		}
+		reader := bufio.NewReader(segment)
+		if first, _ := reader.Peek(1); len(first) == 1 && first[0] == '#' {
+			_, _ = reader.ReadString('\n')
+		}
+		readers = append(readers, reader)
+		return nil
+	}
+	dir := filepath.Dir(filename)
+	completed := 0
+	for i, row := range records {
+		if i == 0 || len(row) < 7 {
+			continue
+		}
+		segmentName := filepath.Join(dir, row[1])
+		completed++
+		checksum, err := fileChecksum(segmentName)
+		if err != nil {
+			log.Println(LoggerHdr + "WARNING: segment " + segmentName + " skipped: " + err.Error())
+			continue
+		}
+		if checksum != row[6] {
+			log.Println(LoggerHdr + "WARNING: checksum mismatch for segment " + segmentName + ", its values could be corrupted")
+		}
+		if err := addSegment(segmentName); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	// The segment being written when the client stopped is not in the index
+	segmentName := fmt.Sprintf("%s%s%04d.csv", prefix, SegmentInfix, completed+1)
+	if _, err := os.Stat(segmentName); err == nil {
+		log.Println(LoggerHdr + "WARNING: segment " + segmentName + " was not completed, its checksum is not verified")
+		if err := addSegment(segmentName); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	f.Reader = io.MultiReader(readers...)
+	return f, nil
+}
+
+// Return the hex encoded SHA-256 checksum of the file
+func fileChecksum(filename string) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testHeader = "#client-send-timestamp,server-timestamp,e2e-rtt\n"
+
+// Segment of a rotated file written by the test, listed in the index if completed
+type testSegment struct {
+	rows      string
+	completed bool
+	checksum  string // the one of the content if empty
+	missing   bool   // listed in the index, but not on disk
+}
+
+func TestOpenRotatedFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		segments []testSegment
+		want     string
+	}{
+		{
+			"completed segments",
+			[]testSegment{{rows: "1,2,1\n", completed: true}, {rows: "3,4,1\n5,6,1\n", completed: true}},
+			testHeader + "1,2,1\n3,4,1\n5,6,1\n",
+		},
+		{
+			"last segment not completed",
+			[]testSegment{{rows: "1,2,1\n", completed: true}, {rows: "3,4,1\n"}},
+			testHeader + "1,2,1\n3,4,1\n",
+		},
+		{
+			"only segment not completed",
+			[]testSegment{{rows: "1,2,1\n"}},
+			testHeader + "1,2,1\n",
+		},
+		{
+			"checksum mismatch",
+			[]testSegment{{rows: "1,2,1\n", completed: true, checksum: "00"}, {rows: "3,4,1\n", completed: true}},
+			testHeader + "1,2,1\n3,4,1\n",
+		},
+		{
+			"missing segment",
+			[]testSegment{{rows: "1,2,1\n", completed: true}, {completed: true, missing: true},
+				{rows: "5,6,1\n", completed: true}},
+			testHeader + "1,2,1\n5,6,1\n",
+		},
+		{
+			"empty segments",
+			[]testSegment{{completed: true}, {rows: "1,2,1\n", completed: true}, {completed: true}},
+			testHeader + "1,2,1\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "rotated")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			prefix := filepath.Join(dir, "results")
+			index := "#segment,file,first-client-send-timestamp,last-client-send-timestamp,records,bytes,sha256\n"
+			for i, segment := range tt.segments {
+				name := fmt.Sprintf("%s%s%04d.csv", filepath.Base(prefix), SegmentInfix, i+1)
+				content := testHeader + segment.rows
+				if !segment.missing {
+					if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if segment.completed {
+					checksum := segment.checksum
+					if checksum == "" {
+						sum := sha256.Sum256([]byte(content))
+						checksum = hex.EncodeToString(sum[:])
+					}
+					index += fmt.Sprintf("%d,%s,,,0,%d,%s\n", i+1, name, len(content), checksum)
+				}
+			}
+			if err := ioutil.WriteFile(prefix+SegmentsIndexSuffix, []byte(index), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := openDataFile(prefix + ".csv")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			if f.Name() != prefix+".csv" {
+				t.Errorf("name = %s, want %s", f.Name(), prefix+".csv")
+			}
+			got, err := ioutil.ReadAll(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("content = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
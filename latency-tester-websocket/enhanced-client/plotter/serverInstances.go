@@ -0,0 +1,43 @@
+package main
+
+import (
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+	"sort"
+)
+
+// Name of the samples whose server instance has not been reported
+const UnknownInstance = "unknown"
+
+// Samples of the E2E plot grouped by the server instance that replied to them
+type instanceSamples map[string]plotter.XYs
+
+func (s instanceSamples) add(instance string, sample plotter.XY) {
+	if instance == "" {
+		instance = UnknownInstance
+	}
+	s[instance] = append(s[instance], sample)
+}
+
+// Add the samples to the plot as points coloured by server instance, so that the switches of the load balancer among
+// the instances show up
+func addInstanceScatters(p *plot.Plot, samples instanceSamples) {
+	instances := make([]string, 0, len(samples))
+	for instance := range samples {
+		instances = append(instances, instance)
+	}
+	sort.Strings(instances)
+	for i, instance := range instances {
+		scatter, err := plotter.NewScatter(samples[instance])
+		errMgmt(err)
+		// The first color is the one of the RTT line
+		scatter.GlyphStyle.Color = plotutil.Color(i + 1)
+		scatter.GlyphStyle.Shape = draw.CircleGlyph{}
+		scatter.GlyphStyle.Radius = vg.Points(4)
+		p.Add(scatter)
+		p.Legend.Add("Instance "+instance, scatter)
+	}
+}
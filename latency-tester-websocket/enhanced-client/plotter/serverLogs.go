@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Times a message went through the server, read from the message logs of the servers
+type serverTimes struct {
+	receive int64
+	send    int64
+	// True if the times are the ones of a relay, replaced by the ones of the server answering the message if logged
+	relayed bool
+}
+
+// Message of the server logs, identified by its stream and its ID besides the client send timestamp, so that the
+// messages of different clients sent at the same time are not mixed up
+type serverLogKey struct {
+	clientSend int64
+	stream     int32
+	id         int32
+}
+
+// Times of the messages logged by the servers, nil if the server logs are not requested
+var serverLogTimes map[serverLogKey]serverTimes
+
+// Times of the messages logged by the servers by client send timestamp only, for the results of the clients that do
+// not report the message IDs
+var serverLogTimesBySend map[int64]serverTimes
+
+// Stream of the messages in the results without the stream column
+const DefaultStream = 1
+
+// Outcome of the messages forwarded by a relay in the server logs
+const RelayedOutcome = "relayed"
+
+// Load the csv and jsonl message logs of the servers found in the folder and in its subfolders, in order to merge them
+// with the client results
+func loadServerLogs(dir string) {
+	serverLogTimes = make(map[serverLogKey]serverTimes)
+	serverLogTimesBySend = make(map[int64]serverTimes)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		switch filepath.Ext(path) {
+		case ".csv":
+			loadCsvServerLog(path)
+		case ".jsonl":
+			loadJsonlServerLog(path)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Println(LoggerHdr+"WARNING: reading the server logs:", err)
+	}
+	log.Println(LoggerHdr + "Loaded the server times of " + strconv.Itoa(len(serverLogTimes)) + " messages")
+}
+
+func loadCsvServerLog(path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Println(LoggerHdr+"WARNING: opening the server log:", err)
+		return
+	}
+	defer f.Close()
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	records, _ := reader.ReadAll()
+	clientColumn := headerColumn(records, "client-send-timestamp")
+	receiveColumn := headerColumn(records, "server-receive-timestamp")
+	sendColumn := headerColumn(records, "server-send-timestamp")
+	outcomeColumn := headerColumn(records, "outcome")
+	streamColumn := headerColumn(records, "stream")
+	idColumn := headerColumn(records, "id")
+	if clientColumn == -1 || receiveColumn == -1 || sendColumn == -1 || streamColumn == -1 || idColumn == -1 {
+		return
+	}
+	for _, row := range records[1:] {
+		if len(row) <= clientColumn || len(row) <= receiveColumn || len(row) <= sendColumn ||
+			len(row) <= streamColumn || len(row) <= idColumn {
+			continue
+		}
+		clientSend, err := strconv.ParseInt(row[clientColumn], 10, 64)
+		if err != nil {
+			continue
+		}
+		stream, _ := strconv.ParseInt(row[streamColumn], 10, 32)
+		id, _ := strconv.ParseInt(row[idColumn], 10, 32)
+		receive, _ := strconv.ParseInt(row[receiveColumn], 10, 64)
+		send, _ := strconv.ParseInt(row[sendColumn], 10, 64)
+		relayed := outcomeColumn != -1 && len(row) > outcomeColumn && row[outcomeColumn] == RelayedOutcome
+		storeServerTimes(serverLogKey{clientSend: clientSend, stream: int32(stream), id: int32(id)},
+			serverTimes{receive: receive, send: send, relayed: relayed})
+	}
+}
+
+func loadJsonlServerLog(path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Println(LoggerHdr+"WARNING: opening the server log:", err)
+		return
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var line struct {
+			Stream        int32  `json:"stream"`
+			Id            int32  `json:"id"`
+			ClientSend    int64  `json:"client_send_timestamp"`
+			ServerReceive int64  `json:"server_receive_timestamp"`
+			ServerSend    int64  `json:"server_send_timestamp"`
+			Outcome       string `json:"outcome"`
+		}
+		if strings.TrimSpace(scanner.Text()) == "" || json.Unmarshal(scanner.Bytes(), &line) != nil {
+			continue
+		}
+		storeServerTimes(serverLogKey{clientSend: line.ClientSend, stream: line.Stream, id: line.Id},
+			serverTimes{receive: line.ServerReceive, send: line.ServerSend, relayed: line.Outcome == RelayedOutcome})
+	}
+}
+
+// Store the times of the message logged first, the duplicated responses being logged again, unless they are the ones
+// of a relay and the server answering the message logged it too
+func storeServerTimes(key serverLogKey, times serverTimes) {
+	if stored, present := serverLogTimes[key]; !present || !times.relayed && stored.relayed {
+		serverLogTimes[key] = times
+	}
+	if stored, present := serverLogTimesBySend[key.clientSend]; !present || !times.relayed && stored.relayed {
+		serverLogTimesBySend[key.clientSend] = times
+	}
+}
+
+// Return the uplink, server residence and downlink times in milliseconds of the client result, merging it with the
+// server logs, false if the server did not log the message. The results without the id column are matched by their
+// client send timestamp only
+func serverLogDecomposition(row []string, rtt float64, streamColumn, idColumn int) (float64, float64, float64, bool) {
+	clientSend, err := strconv.ParseInt(row[0], 10, 64)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	var times serverTimes
+	var present bool
+	if idColumn != -1 && idColumn < len(row) {
+		key := serverLogKey{clientSend: clientSend, stream: DefaultStream}
+		if streamColumn != -1 && streamColumn < len(row) {
+			stream, _ := strconv.ParseInt(row[streamColumn], 10, 32)
+			key.stream = int32(stream)
+		}
+		id, _ := strconv.ParseInt(row[idColumn], 10, 32)
+		key.id = int32(id)
+		times, present = serverLogTimes[key]
+	} else {
+		times, present = serverLogTimesBySend[clientSend]
+	}
+	if !present {
+		return 0, 0, 0, false
+	}
+	uplink := float64(times.receive-clientSend) / 1e6
+	residence := float64(times.send-times.receive) / 1e6
+	return uplink, residence, rtt - uplink - residence, true
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/richiMarchi/latency-tester/enhanced-client/client/prober"
+)
+
+// Entry of the intervals sweep: a fixed send interval in milliseconds or a traffic profile understood by the client,
+// such as "burst:5/100" (5 messages back to back every 100ms), "onoff:10/2000/3000" (a message every 10ms for 2s,
+// then 3s of silence) or "ramp:100/10" (send interval from 100ms to 10ms throughout the step)
+type IntervalData struct {
+	Millis  int
+	Profile string
+}
+
+func (i *IntervalData) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&i.Millis); err == nil {
+		return nil
+	}
+	i.Millis = 0
+	if err := unmarshal(&i.Profile); err != nil {
+		return err
+	}
+	_, _, err := i.profileParams()
+	return err
+}
+
+// Value as written in the settings file
+func (i IntervalData) String() string {
+	if i.Profile == "" {
+		return strconv.Itoa(i.Millis)
+	}
+	return i.Profile
+}
+
+// Value usable in the file names
+func (i IntervalData) Label() string {
+	return strings.NewReplacer(":", "_", "/", "_").Replace(i.String())
+}
+
+// Client flag selecting the interval or the profile
+func (i IntervalData) ClientArg() string {
+	if i.Profile == "" {
+		return "-interval=" + strconv.Itoa(i.Millis)
+	}
+	return "-profile=" + i.Profile
+}
+
+// Number of messages to send in order for the step to last the requested seconds
+func (i IntervalData) Repetitions(stepDuration int) int {
+	stepMs := int((time.Duration(stepDuration) * time.Second).Milliseconds())
+	if i.Profile == "" {
+		return stepMs / i.Millis
+	}
+	kind, params, _ := i.profileParams()
+	switch kind {
+	case prober.BurstProfile:
+		return maxInt(stepMs/params[1], 1) * params[0]
+	case prober.OnOffProfile:
+		perPeriod := maxInt((params[1]+params[0]-1)/params[0], 1)
+		return maxInt(stepMs/(params[1]+params[2]), 1) * perPeriod
+	case prober.RampProfile:
+		// The step lasts as many intervals as the ones of average length fitting in it
+		return maxInt(2*stepMs/(params[0]+params[1]), 1) + 1
+	default:
+		return 0
+	}
+}
+
+// Split the profile into its kind and its parameters, checked as the client does
+func (i IntervalData) profileParams() (string, []int, error) {
+	kind, parsed, err := prober.ProfileParams(i.Profile)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid interval %q, expected milliseconds or a traffic profile: %v", i.Profile, err)
+	}
+	params := make([]int, len(parsed))
+	for j, param := range parsed {
+		params[j] = int(param)
+	}
+	return kind, params, nil
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
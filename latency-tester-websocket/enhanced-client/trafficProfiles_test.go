@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestIntervalDataRepetitions(t *testing.T) {
+	tests := []struct {
+		interval     IntervalData
+		stepDuration int
+		want         int
+	}{
+		{IntervalData{Millis: 20}, 10, 500},
+		{IntervalData{Millis: 3000}, 10, 3},
+		{IntervalData{Profile: "burst:5/100"}, 10, 500},
+		{IntervalData{Profile: "burst:5/20000"}, 10, 5},
+		{IntervalData{Profile: "onoff:10/2000/3000"}, 10, 400},
+		{IntervalData{Profile: "onoff:10/2000/3000"}, 1, 200},
+		{IntervalData{Profile: "onoff:10/5/95"}, 1, 10},
+		{IntervalData{Profile: "ramp:100/10"}, 10, 182},
+		{IntervalData{Profile: "ramp:1000/1000"}, 1, 2},
+		{IntervalData{Profile: "sine:100/10"}, 10, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.interval.Repetitions(tt.stepDuration); got != tt.want {
+			t.Errorf("%s in %ds: repetitions = %d, want %d", tt.interval, tt.stepDuration, got, tt.want)
+		}
+	}
+}
+
+func TestIntervalDataUnmarshal(t *testing.T) {
+	tests := []struct {
+		value string
+		want  IntervalData
+		err   bool
+	}{
+		{"20", IntervalData{Millis: 20}, false},
+		{`"burst:5/100"`, IntervalData{Profile: "burst:5/100"}, false},
+		{"onoff:10/2000/3000", IntervalData{Profile: "onoff:10/2000/3000"}, false},
+		{`"burst:5/0"`, IntervalData{}, true},
+		{`"ramp:0/10"`, IntervalData{}, true},
+		{`"fast"`, IntervalData{}, true},
+	}
+	for _, tt := range tests {
+		var got IntervalData
+		err := yaml.Unmarshal([]byte(tt.value), &got)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: error = %v, want error %v", tt.value, err, tt.err)
+			continue
+		}
+		if !tt.err && got != tt.want {
+			t.Errorf("%s: interval = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package echo
+
+import (
+	"errors"
+	"github.com/gorilla/websocket"
+	"github.com/richiMarchi/latency-tester/server/serialization/protobuf"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"hash/crc32"
+	"math/rand"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Faults the server can inject in the replies
+const (
+	DropFault      = "drop"
+	DelayFault     = "delay"
+	DuplicateFault = "duplicate"
+	ReorderFault   = "reorder"
+	CorruptFault   = "corrupt"
+	CloseFault     = "close"
+)
+
+// Fault injected in a percentage of the replies or periodically, optionally only inside a window of the session
+type faultRule struct {
+	fault   string
+	percent float64
+	// If set, the fault is injected in the first reply after each period instead
+	period time.Duration
+	// Delay of the replies, for the delay fault only
+	delay time.Duration
+	// Window since the start of the session, without end if to is 0
+	from time.Duration
+	to   time.Duration
+}
+
+// Parse the faults to inject, as a comma separated list of fault:rate[/delay][@from-to]. The rate is a percentage of
+// the replies or a period (e.g. 30s), the delay is in milliseconds and the window is relative to the session start
+func parseFaults(value string) ([]faultRule, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var rules []faultRule
+	for _, item := range strings.Split(value, ",") {
+		parts := strings.SplitN(item, ":", 2)
+		if len(parts) != 2 {
+			return nil, errors.New("invalid fault " + strconv.Quote(item) + ", expected fault:rate")
+		}
+		rule := faultRule{fault: parts[0]}
+		switch rule.fault {
+		case DropFault, DelayFault, DuplicateFault, ReorderFault, CorruptFault, CloseFault:
+		default:
+			return nil, errors.New("unknown fault " + strconv.Quote(rule.fault) + ", allowed ones are drop, delay, " +
+				"duplicate, reorder, corrupt and close")
+		}
+		spec := parts[1]
+		if at := strings.Index(spec, "@"); at != -1 {
+			window := strings.SplitN(spec[at+1:], "-", 2)
+			spec = spec[:at]
+			var fromErr, toErr error
+			rule.from, fromErr = time.ParseDuration(window[0])
+			if len(window) == 2 && window[1] != "" {
+				rule.to, toErr = time.ParseDuration(window[1])
+			}
+			if fromErr != nil || toErr != nil || rule.to != 0 && rule.to <= rule.from {
+				return nil, errors.New("invalid window of the fault " + strconv.Quote(item) + ", expected @from-to")
+			}
+		}
+		params := strings.Split(spec, "/")
+		if rule.fault == DelayFault {
+			if len(params) != 2 {
+				return nil, errors.New("invalid fault " + strconv.Quote(item) + ", expected delay:rate/milliseconds")
+			}
+			ms, err := strconv.ParseFloat(params[1], 64)
+			if err != nil || ms < 0 {
+				return nil, errors.New("invalid delay of the fault " + strconv.Quote(item))
+			}
+			rule.delay = milliseconds(ms)
+		} else if len(params) != 1 {
+			return nil, errors.New("invalid fault " + strconv.Quote(item) + ", only the delay has a parameter")
+		}
+		if period, err := time.ParseDuration(params[0]); err == nil {
+			if period <= 0 {
+				return nil, errors.New("invalid period of the fault " + strconv.Quote(item))
+			}
+			rule.period = period
+		} else {
+			percent, err := strconv.ParseFloat(params[0], 64)
+			if err != nil || percent < 0 || percent > 100 {
+				return nil, errors.New("invalid rate of the fault " + strconv.Quote(item) +
+					", expected a percentage or a period")
+			}
+			rule.percent = percent
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Open the file the injected faults are logged to
+func (s *Server) openFaultsLog(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString("#timestamp,remote-address,stream,id,fault\n")
+	s.faultsLog.file = f
+	return err
+}
+
+// Close the file the injected faults are logged to, if any, so that the faults injected afterwards are not stored
+func (s *Server) closeFaultsLog() {
+	s.faultsLog.Lock()
+	defer s.faultsLog.Unlock()
+	if s.faultsLog.file == nil {
+		return
+	}
+	if err := s.faultsLog.file.Close(); err != nil {
+		s.logger.Println("faultsLog: ", err)
+	}
+	s.faultsLog.file = nil
+}
+
+// Faults injected in the replies of a session. The delayed replies are written by their timers, hence the writes are
+// serialized by the mutex
+type faultInjector struct {
+	sync.Mutex
+	server *Server
+	remote string
+	start  time.Time
+	// Time of the last injection of each periodic rule
+	last   []time.Time
+	random *rand.Rand
+	// Reply held back to be sent after the next one
+	held *heldReply
+	// Log the replies are stored in once sent, or not, nil if not requested
+	messages *messageLog
+	// Replies whose delay has not elapsed yet
+	delayed sync.WaitGroup
+}
+
+// Reply held back by the reorder fault, with its message log entry
+type heldReply struct {
+	mt      int
+	message []byte
+	logged  loggedMessage
+}
+
+// Return the fault injector of the session, nil if no fault has to be injected
+func (s *Server) newFaultInjector(remote net.Addr, messages *messageLog) *faultInjector {
+	if len(s.faultRules) == 0 {
+		return nil
+	}
+	start := getTimestamp()
+	f := &faultInjector{
+		server:   s,
+		remote:   remote.String(),
+		start:    start,
+		last:     make([]time.Time, len(s.faultRules)),
+		random:   rand.New(rand.NewSource(start.UnixNano())),
+		messages: messages,
+	}
+	for i := range f.last {
+		f.last[i] = start
+	}
+	return f
+}
+
+// Choose the faults to inject in the next reply
+func (f *faultInjector) pick() []faultRule {
+	now := getTimestamp()
+	elapsed := now.Sub(f.start)
+	var picked []faultRule
+	for i, rule := range f.server.faultRules {
+		if elapsed < rule.from || rule.to != 0 && elapsed >= rule.to {
+			continue
+		}
+		if rule.period != 0 {
+			if now.Sub(f.last[i]) >= rule.period {
+				f.last[i] = now
+				picked = append(picked, rule)
+			}
+		} else if f.random.Float64()*100 < rule.percent {
+			picked = append(picked, rule)
+		}
+	}
+	return picked
+}
+
+// Set the checksum of the payload of the reply and corrupt it if requested, copying the payload shared by the replies
+func (f *faultInjector) prepare(jsonMap *protobuf.DataJSON, faults []faultRule) {
+	checksum := crc32.ChecksumIEEE(jsonMap.Payload)
+	if injected(faults, CorruptFault) {
+		if len(jsonMap.Payload) > 0 {
+			corrupted := make([]byte, len(jsonMap.Payload))
+			copy(corrupted, jsonMap.Payload)
+			corrupted[f.random.Intn(len(corrupted))] ^= 0xFF
+			jsonMap.Payload = corrupted
+		} else {
+			checksum ^= 1
+		}
+	}
+	jsonMap.PayloadChecksum = wrapperspb.UInt32(checksum)
+}
+
+// Return the time the reply has to be held back for by the delay faults, 0 if none
+func (f *faultInjector) delayOf(faults []faultRule) time.Duration {
+	var delay time.Duration
+	for _, rule := range faults {
+		if rule.fault == DelayFault {
+			delay += rule.delay
+		}
+	}
+	return delay
+}
+
+// Send the reply once its delay has elapsed, without holding back the replies to the following messages. The send
+// function takes the send timestamp, so that the delay is part of the server residence time instead of looking like a
+// slower downlink
+func (f *faultInjector) later(delay time.Duration, send func()) {
+	f.delayed.Add(1)
+	time.AfterFunc(delay, func() {
+		defer f.delayed.Done()
+		send()
+	})
+}
+
+// End the injection once the session is over, waiting for the delayed replies and logging the held one as dropped
+func (f *faultInjector) end() {
+	f.delayed.Wait()
+	f.Lock()
+	defer f.Unlock()
+	f.discard()
+}
+
+// Send the reply injecting the faults, storing it in the message log with its outcome once sent, or not. The request
+// size in bytes is the one of the message it replies to. False if the connection has been closed by a fault or by an
+// error
+func (f *faultInjector) write(c *websocket.Conn, mt int, message []byte, jsonMap *protobuf.DataJSON, requestSize int,
+	faults []faultRule) bool {
+	f.Lock()
+	defer f.Unlock()
+	for _, rule := range faults {
+		f.server.logInjection(f.remote, jsonMap.StreamId, jsonMap.Id, rule.fault)
+	}
+	if injected(faults, CloseFault) {
+		// Abrupt close, without the close handshake
+		c.UnderlyingConn().Close()
+		f.messages.write(loggedReply(jsonMap, requestSize, len(message), ClosedOutcome))
+		f.discard()
+		return false
+	}
+	if injected(faults, DropFault) {
+		f.messages.write(loggedReply(jsonMap, requestSize, len(message), DroppedOutcome))
+		// The held reply is not held further, the dropped one being the one it had to follow
+		return f.flush(c)
+	}
+	if injected(faults, ReorderFault) && f.held == nil {
+		f.held = &heldReply{mt: mt, message: message,
+			logged: loggedReply(jsonMap, requestSize, len(message), ReorderedOutcome)}
+		return true
+	}
+	if err := writeResponse(c, mt, message); err != nil {
+		f.server.logger.Println("write: ", err)
+		f.messages.write(loggedReply(jsonMap, requestSize, len(message), FailedOutcome))
+		return false
+	}
+	f.messages.write(loggedReply(jsonMap, requestSize, len(message), sentOutcome(faults)))
+	if injected(faults, DuplicateFault) {
+		if err := writeResponse(c, mt, message); err != nil {
+			f.server.logger.Println("write: ", err)
+			f.messages.write(loggedReply(jsonMap, requestSize, len(message), FailedOutcome))
+			return false
+		}
+		f.messages.write(loggedReply(jsonMap, requestSize, len(message), DuplicatedOutcome))
+	}
+	return f.flush(c)
+}
+
+// Return the outcome of the reply sent with the faults, the ones altering it joined by a plus
+func sentOutcome(faults []faultRule) string {
+	var outcomes []string
+	if injected(faults, DelayFault) {
+		outcomes = append(outcomes, DelayedOutcome)
+	}
+	if injected(faults, CorruptFault) {
+		outcomes = append(outcomes, CorruptedOutcome)
+	}
+	if len(outcomes) == 0 {
+		return SentOutcome
+	}
+	return strings.Join(outcomes, "+")
+}
+
+// Send the reply held back by the reorder fault, if any, false if the connection has been closed by an error
+func (f *faultInjector) flush(c *websocket.Conn) bool {
+	if f.held == nil {
+		return true
+	}
+	// If the write fails the reply is left held, to be logged as dropped when the session ends
+	if err := writeResponse(c, f.held.mt, f.held.message); err != nil {
+		f.server.logger.Println("write: ", err)
+		return false
+	}
+	f.messages.write(f.held.logged)
+	f.held = nil
+	return true
+}
+
+// Log the reply held back by the reorder fault as dropped, since the session ended before it could be sent
+func (f *faultInjector) discard() {
+	if f.held == nil {
+		return
+	}
+	f.server.logInjection(f.remote, f.held.logged.stream, f.held.logged.id, DropFault)
+	f.held.logged.outcome = DroppedOutcome
+	f.messages.write(f.held.logged)
+	f.held = nil
+}
+
+func injected(faults []faultRule, fault string) bool {
+	for _, rule := range faults {
+		if rule.fault == fault {
+			return true
+		}
+	}
+	return false
+}
+
+// Log the injected fault, in the faults log file too if requested
+func (s *Server) logInjection(remote string, stream, id int32, fault string) {
+	s.logger.Println("fault:", fault, "- stream", stream, "- message", id, "-", remote)
+	s.faultsLog.Lock()
+	defer s.faultsLog.Unlock()
+	if s.faultsLog.file == nil {
+		return
+	}
+	s.faultsLog.file.WriteString(strconv.FormatInt(getTimestamp().UnixNano(), 10) + "," + remote + "," +
+		strconv.Itoa(int(stream)) + "," + strconv.Itoa(int(id)) + "," + fault + "\n")
+}
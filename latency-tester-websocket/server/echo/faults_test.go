@@ -0,0 +1,58 @@
+package echo
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseFaults(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []faultRule
+		err   bool
+	}{
+		{"", nil, false},
+		{"drop:5", []faultRule{{fault: DropFault, percent: 5}}, false},
+		{"duplicate:0.5", []faultRule{{fault: DuplicateFault, percent: 0.5}}, false},
+		{"corrupt:100", []faultRule{{fault: CorruptFault, percent: 100}}, false},
+		{"close:30s", []faultRule{{fault: CloseFault, period: 30 * time.Second}}, false},
+		{"delay:10/250", []faultRule{{fault: DelayFault, percent: 10, delay: 250 * time.Millisecond}}, false},
+		{"delay:10/0.5", []faultRule{{fault: DelayFault, percent: 10, delay: 500 * time.Microsecond}}, false},
+		{"delay:1m/100", []faultRule{{fault: DelayFault, period: time.Minute, delay: 100 * time.Millisecond}}, false},
+		{"drop:50@10s-20s", []faultRule{{fault: DropFault, percent: 50, from: 10 * time.Second, to: 20 * time.Second}},
+			false},
+		{"close:5s@1m-", []faultRule{{fault: CloseFault, period: 5 * time.Second, from: time.Minute}}, false},
+		{"reorder:5@1m", []faultRule{{fault: ReorderFault, percent: 5, from: time.Minute}}, false},
+		{"delay:10/20@1s-2s", []faultRule{{fault: DelayFault, percent: 10, delay: 20 * time.Millisecond,
+			from: time.Second, to: 2 * time.Second}}, false},
+		{"drop:5,reorder:10s", []faultRule{{fault: DropFault, percent: 5},
+			{fault: ReorderFault, period: 10 * time.Second}}, false},
+		{"drop", nil, true},
+		{"lose:5", nil, true},
+		{"drop:-1", nil, true},
+		{"drop:101", nil, true},
+		{"drop:often", nil, true},
+		{"drop:0s", nil, true},
+		{"drop:-5s", nil, true},
+		{"drop:5/10", nil, true},
+		{"delay:5", nil, true},
+		{"delay:5/-1", nil, true},
+		{"delay:5/soon", nil, true},
+		{"drop:5@later", nil, true},
+		{"drop:5@20s-10s", nil, true},
+		{"drop:5@10s-10s", nil, true},
+		{"drop:5,", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseFaults(tt.value)
+			if (err != nil) != tt.err {
+				t.Fatalf("error = %v, want error %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rules = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
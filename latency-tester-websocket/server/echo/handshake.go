@@ -0,0 +1,161 @@
+package echo
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/richiMarchi/latency-tester/server/serialization/protobuf"
+	"google.golang.org/protobuf/proto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version of the protocol spoken by the server, the legacy handshake being the version 1
+const ProtocolVersion = 2
+
+// Behaviours of the server the client can request in the handshake
+const (
+	ResponseSizeBehaviour    = "response-size"
+	StreamsBehaviour         = "streams"
+	ProcessingDelayBehaviour = "processing-delay"
+	HopsBehaviour            = "hops"
+)
+
+var capabilities = []string{ResponseSizeBehaviour, StreamsBehaviour, ProcessingDelayBehaviour, HopsBehaviour}
+
+// Parameters of the session agreed in the handshake
+type sessionParams struct {
+	responseBytes int
+	pattern       protobuf.PayloadPattern
+	behaviours    []string
+	delay         *protobuf.ProcessingDelay
+	legacy        bool
+	// Connection towards the upstream server, if the server is a relay
+	upstream *websocket.Conn
+}
+
+// True if the behaviour has been requested in the handshake
+func (p sessionParams) requested(behaviour string) bool {
+	for _, requested := range p.behaviours {
+		if requested == behaviour {
+			return true
+		}
+	}
+	return false
+}
+
+// Read the handshake of the session and reply to it. The legacy handshake, the response size as a decimal string in a
+// text message, is still accepted without reply
+func (s *Server) handshake(c *websocket.Conn) (sessionParams, error) {
+	mt, msg, err := c.ReadMessage()
+	if err != nil {
+		return sessionParams{}, errors.New("read: " + err.Error())
+	}
+	if mt == websocket.TextMessage {
+		responseBytes, err := strconv.Atoi(string(msg))
+		if err != nil || responseBytes < 0 {
+			refuse(c, fmt.Sprintf("invalid legacy handshake %q", msg))
+			return sessionParams{}, fmt.Errorf("invalid legacy handshake %q", msg)
+		}
+		params := sessionParams{responseBytes: responseBytes, legacy: true}
+		if s.config.Upstream != "" {
+			if params.upstream, err = s.dialUpstream(&protobuf.Handshake{
+				Version:      ProtocolVersion,
+				ResponseSize: int32(responseBytes),
+			}); err != nil {
+				refuse(c, "upstream: "+err.Error())
+				return sessionParams{}, errors.New("upstream: " + err.Error())
+			}
+		}
+		return params, nil
+	}
+
+	request := &protobuf.Handshake{}
+	if err = proto.Unmarshal(msg, request); err != nil {
+		refuse(c, "invalid handshake: "+err.Error())
+		return sessionParams{}, errors.New("invalid handshake: " + err.Error())
+	}
+	reply := &protobuf.HandshakeReply{
+		Version:      ProtocolVersion,
+		Capabilities: capabilities,
+		Identity:     s.identity,
+	}
+	if request.Version != ProtocolVersion {
+		reply.Error = "unsupported protocol version " + strconv.Itoa(int(request.Version)) + ", the server speaks " +
+			strconv.Itoa(ProtocolVersion)
+	} else if request.ResponseSize < 0 {
+		reply.Error = "invalid response size " + strconv.Itoa(int(request.ResponseSize))
+	} else if unsupported := unsupportedBehaviours(request.Behaviours); len(unsupported) > 0 {
+		reply.Error = "unsupported behaviours: " + strings.Join(unsupported, ", ")
+	} else if _, known := protobuf.PayloadPattern_name[int32(request.PayloadPattern)]; !known {
+		reply.Error = "unknown payload pattern " + strconv.Itoa(int(request.PayloadPattern))
+	} else if request.ProcessingDelay != nil {
+		if err = validateProcessingDelay(request.ProcessingDelay); err != nil {
+			reply.Error = "invalid processing delay: " + err.Error()
+		}
+	}
+	var up *websocket.Conn
+	if reply.Error == "" && s.config.Upstream != "" {
+		// The session is forwarded as is, so that the upstream server checks it too
+		if up, err = s.dialUpstream(request); err != nil {
+			reply.Error = "upstream: " + err.Error()
+		}
+	}
+	replyMsg, _ := proto.Marshal(reply)
+	if err = c.WriteMessage(websocket.BinaryMessage, replyMsg); err != nil {
+		if up != nil {
+			up.Close()
+		}
+		return sessionParams{}, errors.New("write handshake reply: " + err.Error())
+	}
+	if reply.Error != "" {
+		refuse(c, reply.Error)
+		return sessionParams{}, errors.New(reply.Error)
+	}
+	return sessionParams{
+		responseBytes: int(request.ResponseSize),
+		pattern:       request.PayloadPattern,
+		behaviours:    request.Behaviours,
+		delay:         request.ProcessingDelay,
+		upstream:      up,
+	}, nil
+}
+
+// Close the session with a protocol error, reporting the reason to the client
+func refuse(c *websocket.Conn, reason string) {
+	// The reason of a close frame is limited to 123 bytes
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	_ = c.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseProtocolError, reason),
+		time.Now().Add(time.Second))
+}
+
+// Return the requested behaviours the server does not support
+func unsupportedBehaviours(requested []string) []string {
+	var unsupported []string
+	for _, behaviour := range requested {
+		supported := false
+		for _, capability := range capabilities {
+			if behaviour == capability {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			unsupported = append(unsupported, behaviour)
+		}
+	}
+	return unsupported
+}
+
+// Create a payload of the given size, random to avoid compression unless the zeros are requested
+func newPayload(size int, pattern protobuf.PayloadPattern) []byte {
+	payload := make([]byte, size)
+	if pattern == protobuf.PayloadPattern_RANDOM {
+		_, _ = rand.Read(payload)
+	}
+	return payload
+}
@@ -0,0 +1,43 @@
+package echo
+
+import (
+	"github.com/richiMarchi/latency-tester/server/serialization/protobuf"
+	"log"
+	"os"
+)
+
+// Environment variables describing where the server runs, set by the Kubernetes deployment
+const (
+	InstanceEnv = "INSTANCE_ID"
+	PodEnv      = "POD_NAME"
+	NodeEnv     = "NODE_NAME"
+	RegionEnv   = "REGION"
+)
+
+// Build the identity of the server. The instance ID is the flag value if set, otherwise the one of the environment,
+// the pod name or the hostname, in this order
+func newServerIdentity(instance string) *protobuf.ServerIdentity {
+	hostname, _ := os.Hostname()
+	id := &protobuf.ServerIdentity{
+		Software: "latency-tester-server",
+		Hostname: hostname,
+		Instance: instance,
+		Pod:      os.Getenv(PodEnv),
+		Node:     os.Getenv(NodeEnv),
+		Region:   os.Getenv(RegionEnv),
+	}
+	for _, fallback := range []string{os.Getenv(InstanceEnv), id.Pod, id.Hostname} {
+		if id.Instance == "" {
+			id.Instance = fallback
+		}
+	}
+	return id
+}
+
+func printIdentity(logger *log.Logger, id *protobuf.ServerIdentity) {
+	logger.Println("Instance:", id.Instance)
+	logger.Println("Hostname:", id.Hostname)
+	if id.Pod != "" || id.Node != "" || id.Region != "" {
+		logger.Println("Pod:", id.Pod, "- Node:", id.Node, "- Region:", id.Region)
+	}
+}
@@ -0,0 +1,166 @@
+package echo
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"github.com/richiMarchi/latency-tester/server/serialization/protobuf"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outcomes of the replies stored in the message logs
+const (
+	SentOutcome       = "sent"
+	DelayedOutcome    = "delayed"
+	CorruptedOutcome  = "corrupted"
+	DuplicatedOutcome = "duplicated"
+	ReorderedOutcome  = "reordered"
+	DroppedOutcome    = "dropped"
+	ClosedOutcome     = "closed"
+	FailedOutcome     = "failed"
+	RelayedOutcome    = "relayed"
+)
+
+// Interval the buffered messages are written to the message log at
+const MessageLogFlushInterval = time.Second
+
+// Log of the messages of a session, storing the times they went through the server for the one-way analysis. The
+// messages are buffered and flushed periodically, so that the session does not wait for a write per message
+type messageLog struct {
+	sync.Mutex
+	file   *os.File
+	writer *bufio.Writer
+	format string
+	remote string
+	stop   chan struct{}
+}
+
+// Check the folder and the format of the message logs
+func checkMessageLog(dir, format string) error {
+	if format != CsvFormat && format != JsonlFormat {
+		return errors.New("unknown format " + strconv.Quote(format) + ", allowed ones are csv and jsonl")
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return errors.New(dir + " is not a folder")
+	}
+	return nil
+}
+
+// Create the message log of the session, nil if the message logs are not requested. The file is named after the
+// session start and the remote address, so that the sessions of the same client do not overwrite each other
+func (s *Server) newMessageLog(remote net.Addr, start time.Time) (*messageLog, error) {
+	if s.config.MessageLog == "" {
+		return nil, nil
+	}
+	name := strconv.FormatInt(start.UnixNano(), 10) + "_" + strings.ReplaceAll(remote.String(), ":", "_") + "." +
+		s.config.MessageLogFormat
+	f, err := os.Create(filepath.Join(s.config.MessageLog, name))
+	if err != nil {
+		return nil, err
+	}
+	l := &messageLog{
+		file:   f,
+		writer: bufio.NewWriter(f),
+		format: s.config.MessageLogFormat,
+		remote: remote.String(),
+		stop:   make(chan struct{}),
+	}
+	if l.format == CsvFormat {
+		_, _ = l.writer.WriteString("#stream,id,client-send-timestamp,server-receive-timestamp,server-send-timestamp," +
+			"request-size,response-size,remote-address,outcome\n")
+	}
+	go l.flushPeriodically()
+	return l, nil
+}
+
+// Message of a session as stored in the message log
+type loggedMessage struct {
+	stream        int32
+	id            int32
+	clientSend    *timestamppb.Timestamp
+	serverReceive *timestamppb.Timestamp
+	serverSend    *timestamppb.Timestamp
+	requestSize   int
+	responseSize  int
+	outcome       string
+}
+
+// Return the message to log for the reply of the echo server, given the sizes in bytes of the request and of the reply
+func loggedReply(jsonMap *protobuf.DataJSON, requestSize, responseSize int, outcome string) loggedMessage {
+	return loggedMessage{
+		stream:        jsonMap.StreamId,
+		id:            jsonMap.Id,
+		clientSend:    jsonMap.ClientTimestamp,
+		serverReceive: jsonMap.ServerReceiveTimestamp,
+		serverSend:    jsonMap.ServerSendTimestamp,
+		requestSize:   requestSize,
+		responseSize:  responseSize,
+		outcome:       outcome,
+	}
+}
+
+// Store the message received from the client once its reply has been sent, or not, nothing if the log is not requested
+func (l *messageLog) write(m loggedMessage) {
+	if l == nil {
+		return
+	}
+	clientSend := m.clientSend.AsTime().UnixNano()
+	serverReceive := m.serverReceive.AsTime().UnixNano()
+	serverSend := m.serverSend.AsTime().UnixNano()
+	l.Lock()
+	defer l.Unlock()
+	if l.format == JsonlFormat {
+		line, _ := json.Marshal(struct {
+			Stream        int32  `json:"stream"`
+			Id            int32  `json:"id"`
+			ClientSend    int64  `json:"client_send_timestamp"`
+			ServerReceive int64  `json:"server_receive_timestamp"`
+			ServerSend    int64  `json:"server_send_timestamp"`
+			RequestSize   int    `json:"request_size"`
+			ResponseSize  int    `json:"response_size"`
+			RemoteAddress string `json:"remote_address"`
+			Outcome       string `json:"outcome"`
+		}{m.stream, m.id, clientSend, serverReceive, serverSend, m.requestSize, m.responseSize, l.remote, m.outcome})
+		_, _ = l.writer.Write(append(line, '\n'))
+		return
+	}
+	_, _ = l.writer.WriteString(strconv.Itoa(int(m.stream)) + "," + strconv.Itoa(int(m.id)) + "," +
+		strconv.FormatInt(clientSend, 10) + "," + strconv.FormatInt(serverReceive, 10) + "," +
+		strconv.FormatInt(serverSend, 10) + "," + strconv.Itoa(m.requestSize) + "," + strconv.Itoa(m.responseSize) + "," +
+		l.remote + "," + m.outcome + "\n")
+}
+
+// Flush the buffered messages and close the file
+func (l *messageLog) Close() {
+	close(l.stop)
+	l.Lock()
+	defer l.Unlock()
+	_ = l.writer.Flush()
+	l.file.Close()
+}
+
+func (l *messageLog) flushPeriodically() {
+	ticker := time.NewTicker(MessageLogFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.Lock()
+			_ = l.writer.Flush()
+			l.Unlock()
+		case <-l.stop:
+			return
+		}
+	}
+}
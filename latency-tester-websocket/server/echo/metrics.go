@@ -0,0 +1,85 @@
+package echo
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Namespace of the metrics exposed on /metrics
+const MetricsNamespace = "latency_tester_server"
+
+var (
+	activeSessions = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "sessions_active",
+		Help:      "Echo and relay sessions currently open",
+	})
+	messagesIn = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "messages_received_total",
+		Help:      "Messages received from the clients, handshakes excluded",
+	})
+	messagesOut = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "messages_sent_total",
+		Help:      "Responses sent to the clients, handshake replies excluded",
+	})
+	bytesIn = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "received_bytes_total",
+		Help:      "Bytes of the messages received from the clients",
+	})
+	bytesOut = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "sent_bytes_total",
+		Help:      "Bytes of the responses sent to the clients",
+	})
+	handshakeFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "handshake_failures_total",
+		Help:      "Sessions refused or dropped during the handshake",
+	})
+	writeErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "write_errors_total",
+		Help:      "Responses that could not be sent to the clients",
+	})
+	// The series of a session are deleted when it ends, so that only the open sessions are exposed
+	echoLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: MetricsNamespace,
+		Name:      "echo_latency_seconds",
+		Help:      "Time between reading a message and sending its response, processing delay included",
+		Buckets:   prometheus.ExponentialBuckets(0.00001, 4, 10),
+	}, []string{"session"})
+)
+
+// Count the message received from a client
+func countReceived(message []byte) {
+	messagesIn.Inc()
+	bytesIn.Add(float64(len(message)))
+}
+
+// Send the response to the client, counting it or the write error
+func writeResponse(c *websocket.Conn, mt int, message []byte) error {
+	if err := c.WriteMessage(mt, message); err != nil {
+		// The responses to the messages still in flight when the session is closing are not errors
+		if err != websocket.ErrCloseSent {
+			writeErrors.Inc()
+		}
+		return err
+	}
+	messagesOut.Inc()
+	bytesOut.Add(float64(len(message)))
+	return nil
+}
+
+// Return the echo latency histogram of the session
+func sessionLatency(session string) prometheus.Observer {
+	return echoLatency.WithLabelValues(session)
+}
+
+// Remove the echo latency histogram of the ended session
+func deleteSessionLatency(session string) {
+	echoLatency.DeleteLabelValues(session)
+}
@@ -0,0 +1,87 @@
+package echo
+
+import (
+	"errors"
+	"github.com/richiMarchi/latency-tester/server/serialization/protobuf"
+	"math"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// Processing time emulated for each message of a session
+type processingModel struct {
+	delay  *protobuf.ProcessingDelay
+	random *rand.Rand
+}
+
+// Result of the busy work, stored so that the loop is not optimised away
+var busySink uint64
+
+// Check the parameters of the processing delay requested in the handshake
+func validateProcessingDelay(delay *protobuf.ProcessingDelay) error {
+	for _, param := range []float64{delay.Milliseconds, delay.MaxMilliseconds, delay.Sigma} {
+		if math.IsNaN(param) || math.IsInf(param, 0) {
+			return errors.New("non-finite processing delay parameters")
+		}
+	}
+	switch delay.Model {
+	case protobuf.DelayModel_NONE:
+	case protobuf.DelayModel_FIXED, protobuf.DelayModel_LOGNORMAL:
+		if delay.Milliseconds < 0 || delay.Sigma < 0 {
+			return errors.New("negative processing delay parameters")
+		}
+	case protobuf.DelayModel_UNIFORM:
+		if delay.Milliseconds < 0 || delay.MaxMilliseconds < delay.Milliseconds {
+			return errors.New("invalid uniform processing delay range")
+		}
+	case protobuf.DelayModel_BUSY:
+		if delay.Iterations < 0 {
+			return errors.New("negative busy work iterations")
+		}
+	default:
+		return errors.New("unknown processing delay model")
+	}
+	return nil
+}
+
+// Return the processing model of the session, nil if no processing time has to be emulated
+func newProcessingModel(delay *protobuf.ProcessingDelay) *processingModel {
+	if delay == nil || delay.Model == protobuf.DelayModel_NONE {
+		return nil
+	}
+	return &processingModel{delay: delay, random: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// Emulate the processing of a message, returning the time actually spent
+func (m *processingModel) process() time.Duration {
+	start := getTimestamp()
+	switch m.delay.Model {
+	case protobuf.DelayModel_FIXED:
+		time.Sleep(milliseconds(m.delay.Milliseconds))
+	case protobuf.DelayModel_UNIFORM:
+		time.Sleep(milliseconds(m.delay.Milliseconds +
+			m.random.Float64()*(m.delay.MaxMilliseconds-m.delay.Milliseconds)))
+	case protobuf.DelayModel_LOGNORMAL:
+		// The median is the exponential of the mean of the underlying normal distribution
+		time.Sleep(milliseconds(m.delay.Milliseconds * math.Exp(m.delay.Sigma*m.random.NormFloat64())))
+	case protobuf.DelayModel_BUSY:
+		busyWork(m.delay.Iterations)
+	}
+	return getTimestamp().Sub(start)
+}
+
+func milliseconds(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// Keep the CPU busy for the given iterations of a xorshift generator
+func busyWork(iterations int64) {
+	x := uint64(88172645463325252)
+	for i := int64(0); i < iterations; i++ {
+		x ^= x << 13
+		x ^= x >> 7
+		x ^= x << 17
+	}
+	atomic.StoreUint64(&busySink, x)
+}
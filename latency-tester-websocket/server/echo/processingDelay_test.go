@@ -0,0 +1,41 @@
+package echo
+
+import (
+	"github.com/richiMarchi/latency-tester/server/serialization/protobuf"
+	"math"
+	"testing"
+)
+
+func TestValidateProcessingDelay(t *testing.T) {
+	tests := []struct {
+		name  string
+		delay *protobuf.ProcessingDelay
+		err   bool
+	}{
+		{"none", &protobuf.ProcessingDelay{}, false},
+		{"fixed", &protobuf.ProcessingDelay{Model: protobuf.DelayModel_FIXED, Milliseconds: 5}, false},
+		{"uniform", &protobuf.ProcessingDelay{Model: protobuf.DelayModel_UNIFORM, Milliseconds: 1, MaxMilliseconds: 3},
+			false},
+		{"lognormal", &protobuf.ProcessingDelay{Model: protobuf.DelayModel_LOGNORMAL, Milliseconds: 5, Sigma: 0.5},
+			false},
+		{"busy", &protobuf.ProcessingDelay{Model: protobuf.DelayModel_BUSY, Iterations: 1000}, false},
+		{"negative fixed", &protobuf.ProcessingDelay{Model: protobuf.DelayModel_FIXED, Milliseconds: -1}, true},
+		{"inverted uniform",
+			&protobuf.ProcessingDelay{Model: protobuf.DelayModel_UNIFORM, Milliseconds: 3, MaxMilliseconds: 1}, true},
+		{"negative busy", &protobuf.ProcessingDelay{Model: protobuf.DelayModel_BUSY, Iterations: -1}, true},
+		{"NaN fixed", &protobuf.ProcessingDelay{Model: protobuf.DelayModel_FIXED, Milliseconds: math.NaN()}, true},
+		{"infinite fixed", &protobuf.ProcessingDelay{Model: protobuf.DelayModel_FIXED, Milliseconds: math.Inf(1)}, true},
+		{"infinite uniform", &protobuf.ProcessingDelay{Model: protobuf.DelayModel_UNIFORM, Milliseconds: 1,
+			MaxMilliseconds: math.Inf(1)}, true},
+		{"NaN sigma", &protobuf.ProcessingDelay{Model: protobuf.DelayModel_LOGNORMAL, Milliseconds: 5,
+			Sigma: math.NaN()}, true},
+		{"unknown model", &protobuf.ProcessingDelay{Model: 42}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateProcessingDelay(tt.delay); (err != nil) != tt.err {
+				t.Errorf("error = %v, want error %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package echo
+
+import (
+	tlsconfig "crypto/tls"
+	"errors"
+	"github.com/gorilla/websocket"
+	"github.com/richiMarchi/latency-tester/server/serialization/protobuf"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Key of the messages forwarded upstream, whose response is still expected
+type messageKey struct {
+	stream int32
+	id     int32
+}
+
+// Times a message has been received from the client and forwarded upstream by the relay, with its size in bytes
+type forwardTimes struct {
+	receive     *timestamppb.Timestamp
+	forward     *timestamppb.Timestamp
+	requestSize int
+}
+
+// Open the connection towards the upstream server and forward the handshake of the session, failing if the upstream
+// server refuses it
+func (s *Server) dialUpstream(request *protobuf.Handshake) (*websocket.Conn, error) {
+	dialer := *websocket.DefaultDialer
+	scheme := "ws"
+	if s.config.UpstreamTls {
+		scheme = "wss"
+		dialer.TLSClientConfig = &tlsconfig.Config{InsecureSkipVerify: true}
+	}
+	u := url.URL{Scheme: scheme, Host: s.config.Upstream, Path: "/echo"}
+	up, _, err := dialer.Dial(u.String(), nil)
+	if err != nil {
+		return nil, errors.New("dial " + s.config.Upstream + ": " + err.Error())
+	}
+	msg, _ := proto.Marshal(request)
+	if err = up.WriteMessage(websocket.BinaryMessage, msg); err != nil {
+		up.Close()
+		return nil, errors.New("handshake with " + s.config.Upstream + ": " + err.Error())
+	}
+	_ = up.SetReadDeadline(time.Now().Add(10 * time.Second))
+	_, msg, err = up.ReadMessage()
+	if closeErr, closed := err.(*websocket.CloseError); closed {
+		up.Close()
+		return nil, errors.New(s.config.Upstream + " refused the session: " + closeErr.Text)
+	}
+	if err != nil {
+		up.Close()
+		return nil, errors.New("handshake with " + s.config.Upstream + ": " + err.Error())
+	}
+	_ = up.SetReadDeadline(time.Time{})
+	reply := &protobuf.HandshakeReply{}
+	if err = proto.Unmarshal(msg, reply); err != nil || reply.Error != "" || reply.Version != ProtocolVersion {
+		up.Close()
+		if reply.Error != "" {
+			return nil, errors.New(s.config.Upstream + " refused the session: " + reply.Error)
+		}
+		return nil, errors.New(s.config.Upstream + " speaks the protocol version " + strconv.Itoa(int(reply.Version)))
+	}
+	if reply.Identity != nil {
+		s.logger.Println("Relaying to", s.config.Upstream, "instance", reply.Identity.Instance)
+	}
+	return up, nil
+}
+
+// Forward the messages of the client to the upstream server and its responses back, appending the timestamps of the
+// relay to the hops of the responses and storing them in the message log if requested
+func (s *Server) relay(c *websocket.Conn, params sessionParams, session *liveSession) {
+	up := params.upstream
+	messages, err := s.newMessageLog(c.RemoteAddr(), getTimestamp())
+	if err != nil {
+		s.logger.Println("message log: ", err)
+	}
+	if messages != nil {
+		defer messages.Close()
+	}
+	// The times of the messages are tracked if they are needed by the hops or by the message log
+	tracked := params.requested(HopsBehaviour) || messages != nil
+	var pending sync.Map
+	// Highest and previous highest IDs answered on each stream, used to forget the messages the upstream server
+	// dropped. Only the reader of the upstream responses accesses them
+	answered := make(map[int32][2]int32)
+	// Closed when the session ends on the client side, before closing the upstream connection
+	closing := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// The client is disconnected if the upstream server goes away
+		defer c.Close()
+		for {
+			mt, message, err := up.ReadMessage()
+			reply := timestamppb.New(getTimestamp())
+			if err != nil {
+				select {
+				case <-closing:
+				default:
+					s.logger.Println("upstream read: ", err)
+				}
+				// The client reconnects without errors if the upstream server is shutting down
+				if websocket.IsCloseError(err, websocket.CloseGoingAway) {
+					_ = c.WriteControl(websocket.CloseMessage,
+						websocket.FormatCloseMessage(websocket.CloseGoingAway, "upstream "+ShutdownReason),
+						time.Now().Add(time.Second))
+				}
+				return
+			}
+			if !tracked {
+				if err = writeResponse(c, mt, message); err != nil {
+					s.logger.Println("write: ", err)
+					return
+				}
+				continue
+			}
+			jsonMap := &protobuf.DataJSON{}
+			_ = proto.Unmarshal(message, jsonMap)
+			key := messageKey{stream: jsonMap.StreamId, id: jsonMap.Id}
+			var times forwardTimes
+			if loaded, present := pending.Load(key); present {
+				pending.Delete(key)
+				times = loaded.(forwardTimes)
+			}
+			if highest := answered[key.stream]; key.id > highest[0] {
+				answered[key.stream] = [2]int32{key.id, highest[0]}
+				forgetDropped(&pending, key.stream, highest[0])
+			}
+			sent := timestamppb.New(getTimestamp())
+			if params.requested(HopsBehaviour) {
+				jsonMap.Hops = append(jsonMap.Hops, &protobuf.Hop{
+					Instance:         s.identity.Instance,
+					ReceiveTimestamp: times.receive,
+					ForwardTimestamp: times.forward,
+					ReplyTimestamp:   reply,
+					SendTimestamp:    sent,
+				})
+				message, _ = proto.Marshal(jsonMap)
+			}
+			// The relay stores its own times, the ones of the upstream servers being in their message logs
+			logged := loggedMessage{
+				stream:        jsonMap.StreamId,
+				id:            jsonMap.Id,
+				clientSend:    jsonMap.ClientTimestamp,
+				serverReceive: times.receive,
+				serverSend:    sent,
+				requestSize:   times.requestSize,
+				responseSize:  len(message),
+				outcome:       RelayedOutcome,
+			}
+			if err = writeResponse(c, mt, message); err != nil {
+				s.logger.Println("write: ", err)
+				logged.outcome = FailedOutcome
+				messages.write(logged)
+				return
+			}
+			messages.write(logged)
+		}
+	}()
+
+	for {
+		mt, message, err := c.ReadMessage()
+		received := timestamppb.New(getTimestamp())
+		if err != nil {
+			s.logger.Println("read: " + err.Error() + "\n")
+			break
+		}
+		countReceived(message)
+		session.received(received.AsTime())
+		if tracked {
+			jsonMap := &protobuf.DataJSON{}
+			_ = proto.Unmarshal(message, jsonMap)
+			pending.Store(messageKey{stream: jsonMap.StreamId, id: jsonMap.Id},
+				forwardTimes{receive: received, forward: timestamppb.New(getTimestamp()), requestSize: len(message)})
+		}
+		if err = up.WriteMessage(mt, message); err != nil {
+			s.logger.Println("upstream write: ", err)
+			break
+		}
+	}
+	close(closing)
+	_ = up.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	up.Close()
+	<-done
+	// The responses still expected will not arrive anymore
+	pending.Range(func(key, _ interface{}) bool {
+		pending.Delete(key)
+		return true
+	})
+}
+
+// Remove the messages of the stream forwarded upstream before the given ID, whose responses have been dropped by the
+// upstream server. The ID is the previous highest answered one, so that a response held back by one message (e.g. by
+// a reorder fault) still finds its times
+func forgetDropped(pending *sync.Map, stream int32, before int32) {
+	pending.Range(func(k, _ interface{}) bool {
+		if key := k.(messageKey); key.stream == stream && key.id < before {
+			pending.Delete(key)
+		}
+		return true
+	})
+}
@@ -0,0 +1,243 @@
+// Package echo implements the latency-tester server: the handshake of the sessions, the echo of the messages with the
+// emulated processing delay, the relay towards an upstream server, the fault injection, the message logs and the
+// metrics. It is used by the server command, and it can be imported to run the server in-process, as the enhanced
+// client does for the calibration.
+package echo
+
+import (
+	"errors"
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/richiMarchi/latency-tester/server/serialization/protobuf"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// Formats of the message logs
+const (
+	CsvFormat   = "csv"
+	JsonlFormat = "jsonl"
+)
+
+// Parameters of the server, the same ones accepted by the server command as flags
+type Config struct {
+	// Address of the upstream server the messages are relayed to, none if empty
+	Upstream    string
+	UpstreamTls bool
+	// Faults to inject, as comma separated fault:rate[/delay][@from-to], none if empty
+	Faults string
+	// File the injected faults are logged to, none if empty
+	FaultsLog string
+	// Folder the per-session message logs are written to, disabled if empty, and their format
+	MessageLog       string
+	MessageLogFormat string
+	// Instance ID reported in the responses, the one of the environment, the pod name or the hostname if empty
+	Instance string
+	// Logger of the sessions, the standard one if nil
+	Logger *log.Logger
+}
+
+// Server handling the echo and relay sessions, through ServeHTTP on the echo endpoint
+type Server struct {
+	config     Config
+	logger     *log.Logger
+	identity   *protobuf.ServerIdentity
+	faultRules []faultRule
+	// File the injected faults are logged to, if requested
+	faultsLog struct {
+		sync.Mutex
+		file *os.File
+	}
+	sessions registry
+	upgrader websocket.Upgrader
+}
+
+// Create the server, checking the configuration and opening the faults log
+func New(config Config) (*Server, error) {
+	if config.MessageLogFormat == "" {
+		config.MessageLogFormat = CsvFormat
+	}
+	s := &Server{
+		config:   config,
+		logger:   config.Logger,
+		identity: newServerIdentity(config.Instance),
+		sessions: registry{open: make(map[int64]*liveSession)},
+	}
+	if s.logger == nil {
+		s.logger = log.New(os.Stderr, "", log.LstdFlags)
+	}
+	var err error
+	if s.faultRules, err = parseFaults(config.Faults); err != nil {
+		return nil, errors.New("faults: " + err.Error())
+	}
+	if config.MessageLog != "" {
+		if err = checkMessageLog(config.MessageLog, config.MessageLogFormat); err != nil {
+			return nil, errors.New("messageLog: " + err.Error())
+		}
+	}
+	if config.FaultsLog != "" {
+		if err = s.openFaultsLog(config.FaultsLog); err != nil {
+			return nil, errors.New("faultsLog: " + err.Error())
+		}
+	}
+	return s, nil
+}
+
+// Identity of the server, replied to the handshakes
+func (s *Server) Identity() *protobuf.ServerIdentity {
+	return s.identity
+}
+
+// Print the configuration of the server
+func (s *Server) PrintConfig() {
+	printIdentity(s.logger, s.identity)
+	if s.config.Upstream != "" {
+		s.logger.Println("Relaying to", s.config.Upstream, "- TLS enabled:", s.config.UpstreamTls)
+	}
+	if len(s.faultRules) > 0 {
+		s.logger.Println("Injected faults:", s.config.Faults)
+	}
+	if s.config.MessageLog != "" {
+		s.logger.Println("Message logs:", s.config.MessageLog, "-", s.config.MessageLogFormat)
+	}
+}
+
+// Close the resources of the server once its sessions have ended
+func (s *Server) Close() {
+	s.closeFaultsLog()
+}
+
+// Serve a session on the echo endpoint
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.Draining() {
+		http.Error(w, ShutdownReason, http.StatusServiceUnavailable)
+		return
+	}
+	c, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.logger.Print("upgrade: ", err)
+		return
+	}
+	defer c.Close()
+	params, err := s.handshake(c)
+	if err != nil {
+		handshakeFailures.Inc()
+		s.logger.Println("handshake: ", err)
+		return
+	}
+	session := s.openSession(c, params)
+	if session == nil {
+		if params.upstream != nil {
+			params.upstream.Close()
+		}
+		return
+	}
+	defer session.close()
+	activeSessions.Inc()
+	defer activeSessions.Dec()
+	s.printLogs(c.RemoteAddr(), params)
+	if params.upstream != nil {
+		s.relay(c, params, session)
+		return
+	}
+	s.echo(c, params, session)
+}
+
+// Echo the messages of the session, injecting the faults and storing them in the message log if requested
+func (s *Server) echo(c *websocket.Conn, params sessionParams, session *liveSession) {
+	payload := newPayload(params.responseBytes, params.pattern)
+	processing := newProcessingModel(params.delay)
+	latency := sessionLatency(c.RemoteAddr().String())
+	defer deleteSessionLatency(c.RemoteAddr().String())
+	messages, err := s.newMessageLog(c.RemoteAddr(), getTimestamp())
+	if err != nil {
+		s.logger.Println("message log: ", err)
+	}
+	if messages != nil {
+		defer messages.Close()
+	}
+	faults := s.newFaultInjector(c.RemoteAddr(), messages)
+	if faults != nil {
+		defer faults.end()
+	}
+
+	for {
+		mt, message, err := c.ReadMessage()
+		received := timestamppb.New(getTimestamp())
+		if err != nil {
+			s.logger.Println("read: " + err.Error() + "\n")
+			return
+		}
+		countReceived(message)
+		session.received(received.AsTime())
+		requestSize := len(message)
+		jsonMap := &protobuf.DataJSON{}
+		_ = proto.Unmarshal(message, jsonMap)
+		jsonMap.Instance = s.identity.Instance
+		jsonMap.ServerReceiveTimestamp = received
+		jsonMap.ProcessingStartTimestamp = timestamppb.New(getTimestamp())
+		if processing != nil {
+			jsonMap.ProcessingDelay = durationpb.New(processing.process())
+		}
+		jsonMap.ProcessingEndTimestamp = timestamppb.New(getTimestamp())
+		// The request can ask for a response size different from the one of the session, empty responses included
+		if jsonMap.ResponseSize != nil && jsonMap.GetResponseSize() >= 0 {
+			if int(jsonMap.GetResponseSize()) > len(payload) {
+				payload = newPayload(int(jsonMap.GetResponseSize()), params.pattern)
+			}
+			jsonMap.Payload = payload[:jsonMap.GetResponseSize()]
+		} else {
+			jsonMap.Payload = payload[:params.responseBytes]
+		}
+		var picked []faultRule
+		if faults != nil {
+			picked = faults.pick()
+			faults.prepare(jsonMap, picked)
+			if delay := faults.delayOf(picked); delay > 0 {
+				faults.later(delay, func() {
+					message := s.stamp(jsonMap, params, received, latency)
+					faults.write(c, mt, message, jsonMap, requestSize, picked)
+				})
+				continue
+			}
+		}
+		message = s.stamp(jsonMap, params, received, latency)
+		if faults != nil {
+			if !faults.write(c, mt, message, jsonMap, requestSize, picked) {
+				return
+			}
+			continue
+		}
+		err = writeResponse(c, mt, message)
+		s.logger.Printf("recv: ACK")
+		if err != nil {
+			s.logger.Println("write: ", err)
+			messages.write(loggedReply(jsonMap, requestSize, len(message), FailedOutcome))
+			return
+		}
+		messages.write(loggedReply(jsonMap, requestSize, len(message), SentOutcome))
+	}
+}
+
+// Take the send timestamp of the reply, right before marshalling it, returning the marshalled reply
+func (s *Server) stamp(jsonMap *protobuf.DataJSON, params sessionParams, received *timestamppb.Timestamp,
+	latency prometheus.Observer) []byte {
+	jsonMap.ServerSendTimestamp = timestamppb.New(getTimestamp())
+	// The legacy server timestamp is the send time, as in the earlier campaigns
+	jsonMap.ServerTimestamp = jsonMap.ServerSendTimestamp
+	if params.requested(HopsBehaviour) {
+		jsonMap.Hops = append(jsonMap.Hops, &protobuf.Hop{
+			Instance:         s.identity.Instance,
+			ReceiveTimestamp: received,
+			SendTimestamp:    jsonMap.ServerSendTimestamp,
+		})
+	}
+	message, _ := proto.Marshal(jsonMap)
+	latency.Observe(jsonMap.ServerSendTimestamp.AsTime().Sub(received.AsTime()).Seconds())
+	return message
+}
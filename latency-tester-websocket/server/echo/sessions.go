@@ -0,0 +1,206 @@
+package echo
+
+import (
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Reason of the close frames sent to the clients when the server shuts down
+const ShutdownReason = "server shutting down"
+
+// Echo or relay session open on the server, listed by the admin API
+type liveSession struct {
+	server        *Server
+	id            int64
+	conn          *websocket.Conn
+	start         time.Time
+	responseBytes int
+	upstream      string
+	// Updated by the session goroutine and read by the admin API, hence accessed atomically
+	messages int64
+	lastSeen int64
+}
+
+// Sessions open on the server, by ID, and whether the server is draining them to shut down
+type registry struct {
+	sync.Mutex
+	// Waits for the open sessions to end
+	sync.WaitGroup
+	lastId   int64
+	open     map[int64]*liveSession
+	draining bool
+}
+
+// Register the session whose handshake has been completed. If the server is shutting down, the session is closed with
+// the going away code instead and nil is returned, since the drain may already be waiting for the registered sessions
+func (server *Server) openSession(c *websocket.Conn, params sessionParams) *liveSession {
+	s := &liveSession{server: server, conn: c, start: getTimestamp(), responseBytes: params.responseBytes}
+	if params.upstream != nil {
+		s.upstream = server.config.Upstream
+	}
+	s.lastSeen = s.start.UnixNano()
+	sessions := &server.sessions
+	sessions.Lock()
+	if sessions.draining {
+		sessions.Unlock()
+		s.sendClose(websocket.CloseGoingAway, ShutdownReason)
+		return nil
+	}
+	sessions.lastId++
+	s.id = sessions.lastId
+	sessions.open[s.id] = s
+	sessions.Add(1)
+	sessions.Unlock()
+	return s
+}
+
+// Unregister the ended session
+func (s *liveSession) close() {
+	sessions := &s.server.sessions
+	sessions.Lock()
+	defer sessions.Unlock()
+	delete(sessions.open, s.id)
+	sessions.Done()
+}
+
+// Track the message received on the session
+func (s *liveSession) received(at time.Time) {
+	atomic.AddInt64(&s.messages, 1)
+	atomic.StoreInt64(&s.lastSeen, at.UnixNano())
+}
+
+// Start the close handshake of the session from outside its goroutine, which ends once the client replies
+func (s *liveSession) sendClose(code int, reason string) {
+	err := s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason),
+		time.Now().Add(time.Second))
+	if err != nil {
+		s.server.logger.Println("write close: ", err)
+	}
+}
+
+// Close the session from outside its goroutine, sending the close frame with the code and the reason first. The
+// session goroutine gets a read error and ends
+func (s *liveSession) kick(code int, reason string) {
+	s.sendClose(code, reason)
+	s.conn.Close()
+}
+
+// Session as returned by the admin API
+type sessionJson struct {
+	Id            int64     `json:"id"`
+	RemoteAddress string    `json:"remote_address"`
+	Start         time.Time `json:"start"`
+	ResponseSize  int       `json:"response_size"`
+	Messages      int64     `json:"messages"`
+	LastSeen      time.Time `json:"last_seen"`
+	Upstream      string    `json:"upstream,omitempty"`
+}
+
+func (s *liveSession) toJson() sessionJson {
+	return sessionJson{
+		Id:            s.id,
+		RemoteAddress: s.conn.RemoteAddr().String(),
+		Start:         s.start,
+		ResponseSize:  s.responseBytes,
+		Messages:      atomic.LoadInt64(&s.messages),
+		LastSeen:      time.Unix(0, atomic.LoadInt64(&s.lastSeen)),
+		Upstream:      s.upstream,
+	}
+}
+
+// Return the open sessions, ordered by ID
+func (s *Server) openSessions() []*liveSession {
+	sessions := &s.sessions
+	sessions.Lock()
+	defer sessions.Unlock()
+	list := make([]*liveSession, 0, len(sessions.open))
+	for _, s := range sessions.open {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].id < list[j].id })
+	return list
+}
+
+// Return the handler of the admin API, to be served on its own address so that it is not exposed with the echo
+// endpoint: GET /sessions lists the open sessions, DELETE /sessions/<id> closes one of them
+func (s *Server) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sessions", s.listSessions)
+	mux.HandleFunc("/sessions/", s.closeSession)
+	return mux
+}
+
+func (s *Server) listSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	list := make([]sessionJson, 0)
+	for _, session := range s.openSessions() {
+		list = append(list, session.toJson())
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+func (s *Server) closeSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/sessions/"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid session ID", http.StatusBadRequest)
+		return
+	}
+	s.sessions.Lock()
+	session, present := s.sessions.open[id]
+	s.sessions.Unlock()
+	if !present {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	s.logger.Println("Closing session", id, "with", session.conn.RemoteAddr(), "on admin request")
+	session.kick(websocket.ClosePolicyViolation, "closed by the administrator")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Close the open sessions with the going away code and refuse the new ones, then wait for the sessions to end up to the
+// deadline, closing the remaining ones abruptly. Return the number of sessions closed abruptly
+func (s *Server) Drain(deadline time.Time) int {
+	s.sessions.Lock()
+	s.sessions.draining = true
+	s.sessions.Unlock()
+	for _, session := range s.openSessions() {
+		session.sendClose(websocket.CloseGoingAway, ShutdownReason)
+	}
+	ended := make(chan struct{})
+	go func() {
+		s.sessions.Wait()
+		close(ended)
+	}()
+	select {
+	case <-ended:
+		return 0
+	case <-time.After(time.Until(deadline)):
+	}
+	remaining := s.openSessions()
+	for _, session := range remaining {
+		session.conn.Close()
+	}
+	return len(remaining)
+}
+
+// True if the server is shutting down and no longer accepts sessions
+func (s *Server) Draining() bool {
+	s.sessions.Lock()
+	defer s.sessions.Unlock()
+	return s.sessions.draining
+}
@@ -0,0 +1,26 @@
+package echo
+
+import (
+	"net"
+	"time"
+)
+
+func getTimestamp() time.Time {
+	return time.Now()
+}
+
+func (s *Server) printLogs(addr net.Addr,
+	params sessionParams) {
+	s.logger.Println("Connection established with", addr)
+	if params.legacy {
+		s.logger.Println("Legacy handshake")
+	} else {
+		s.logger.Println("Protocol version =", ProtocolVersion)
+		s.logger.Println("Payload pattern =", params.pattern)
+		s.logger.Println("Requested behaviours =", params.behaviours)
+		if params.delay != nil {
+			s.logger.Println("Processing delay =", params.delay.String())
+		}
+	}
+	s.logger.Println("Response payload size =", params.responseBytes)
+}
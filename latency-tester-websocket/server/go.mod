@@ -5,5 +5,6 @@ go 1.14
 require (
 	github.com/golang/protobuf v1.5.1
 	github.com/gorilla/websocket v1.4.2
+	github.com/prometheus/client_golang v1.10.0
 	google.golang.org/protobuf v1.26.0
 )
@@ -1,71 +1,65 @@
 package main
 
 import (
-	"crypto/rand"
 	"flag"
-	"github.com/gorilla/websocket"
-	"github.com/richiMarchi/latency-tester/server/serialization/protobuf"
-	"google.golang.org/protobuf/proto"
-	"google.golang.org/protobuf/types/known/timestamppb"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/richiMarchi/latency-tester/server/echo"
 	"log"
 	"net/http"
-	"strconv"
+	"time"
 )
 
 var addr = flag.String("addr", "0.0.0.0:8080", "http service address")
 var tls = flag.Bool("tls", false, "true if tls server")
-
-var upgrader = websocket.Upgrader{}
+var upstream = flag.String("upstream", "", "address of the upstream server the messages are relayed to")
+var upstreamTls = flag.Bool("upstreamTls", false, "true if the upstream server requires tls")
+var faultsSpec = flag.String("faults", "", "faults to inject, as comma separated fault:rate[/delay][@from-to]")
+var faultsLogFile = flag.String("faultsLog", "", "file the injected faults are logged to")
+var messageLogDir = flag.String("messageLog", "", "folder the per-session message logs are written to")
+var messageLogFormat = flag.String("messageLogFormat", echo.CsvFormat, "format of the message logs, csv or jsonl")
+var adminAddr = flag.String("admin", "", "address of the admin API listing and closing the sessions, disabled if empty")
+var shutdownTimeout = flag.Duration("shutdownTimeout", 10*time.Second,
+	"time the open sessions are given to close on SIGTERM before closing them abruptly")
+var instance = flag.String("instance", "", "instance ID reported in the responses (default $"+echo.InstanceEnv+
+	", $"+echo.PodEnv+" or the hostname)")
 
 func main() {
 	flag.Parse()
-	http.HandleFunc("/echo", echo)
+	server, err := echo.New(echo.Config{
+		Upstream:         *upstream,
+		UpstreamTls:      *upstreamTls,
+		Faults:           *faultsSpec,
+		FaultsLog:        *faultsLogFile,
+		MessageLog:       *messageLogDir,
+		MessageLogFormat: *messageLogFormat,
+		Instance:         *instance,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	http.Handle("/echo", server)
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) { return })
+	http.Handle("/metrics", promhttp.Handler())
 	log.Println("Listening to", *addr)
 	log.Println("TLS enabled:", *tls)
-	if *tls {
-		log.Fatal(http.ListenAndServeTLS(*addr, "server.crt", "server.key", nil))
-	} else {
-		log.Fatal(http.ListenAndServe(*addr, nil))
-	}
-}
-
-func echo(w http.ResponseWriter, r *http.Request) {
-	c, err := upgrader.Upgrade(w, r, nil)
-	if err != nil {
-		log.Print("upgrade: ", err)
-		return
-	}
-	_, msg, resErr := c.ReadMessage()
-	if resErr != nil {
-		log.Println("read: ", resErr)
-		return
+	server.PrintConfig()
+	if *adminAddr != "" {
+		log.Println("Admin API listening to", *adminAddr)
+		go func() {
+			log.Fatal(http.ListenAndServe(*adminAddr, server.AdminHandler()))
+		}()
 	}
-	responseBytes, _ := strconv.Atoi(string(msg))
-
-	// Create a random payload to avoid compression
-	payload := make([]byte, responseBytes)
-	_, _ = rand.Read(payload)
-
-	printLogs(c.RemoteAddr(), responseBytes)
-
-	defer c.Close()
-	for {
-		mt, message, err := c.ReadMessage()
-		if err != nil {
-			log.Println("read: " + err.Error() + "\n")
-			return
+	httpServer := &http.Server{Addr: *addr}
+	go func() {
+		var err error
+		if *tls {
+			err = httpServer.ListenAndServeTLS("server.crt", "server.key")
+		} else {
+			err = httpServer.ListenAndServe()
 		}
-		jsonMap := &protobuf.DataJSON{}
-		_ = proto.Unmarshal(message, jsonMap)
-		jsonMap.ServerTimestamp = timestamppb.New(getTimestamp())
-		jsonMap.Payload = payload
-		message, _ = proto.Marshal(jsonMap)
-		err = c.WriteMessage(mt, message)
-		log.Printf("recv: ACK")
-		if err != nil {
-			log.Println("write: ", err)
-			return
+		if err != http.ErrServerClosed {
+			log.Fatal(err)
 		}
-	}
+	}()
+	waitShutdown(httpServer, server, *shutdownTimeout)
 }
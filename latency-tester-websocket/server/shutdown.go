@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"github.com/richiMarchi/latency-tester/server/echo"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Wait for SIGTERM or SIGINT, then stop accepting sessions and drain the open ones, closing them with the going away
+// code and waiting for them to end up to the timeout
+func waitShutdown(httpServer *http.Server, server *echo.Server, timeout time.Duration) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	sig := <-signals
+	log.Println("Shutting down on", sig, "- draining the sessions for up to", timeout)
+	deadline := time.Now().Add(timeout)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	// The upgraded connections are not tracked by the http server, hence it only stops listening here
+	if err := httpServer.Shutdown(ctx); err != nil {
+		log.Println("shutdown: ", err)
+	}
+	if closed := server.Drain(deadline); closed > 0 {
+		log.Println(closed, "sessions closed abruptly after the timeout")
+	}
+	server.Close()
+	log.Println("Shutdown completed")
+}